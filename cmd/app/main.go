@@ -1,35 +1,55 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log/slog"
 	"os"
 
 	"go-payment-service/internal/app"
+	"go-payment-service/internal/config"
 )
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		slog.Error("error loading configuration", slog.Any("error", err))
+		os.Exit(1)
 	}
 
 	logLevel := slog.LevelInfo
-	if os.Getenv("APP_ENV") == "development" {
+	if cfg.Development() {
 		logLevel = slog.LevelDebug
 	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	// Records logged with the context of a request carry its request ID and trace ID
+	logger := slog.New(app.NewContextLogHandler(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: logLevel,
-	}))
+	})))
 
 	slog.SetDefault(logger)
 
-	srv := app.NewServer()
+	srv := app.NewServer(cfg)
+
+	// Reload the gateways and routing settings on SIGHUP or when the configuration files change
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config.Watch(ctx, os.Args[1:], cfg, func(cfg config.Config) {
+		if err := srv.Reload(cfg); err != nil {
+			slog.Error("error reloading configuration", slog.Any("error", err))
+		}
+	})
 
 	slog.Info("starting app", slog.Any("mode", logLevel))
 
 	// Start server
-	if err := srv.Start(port); err != nil {
-		logger.Error("error starting server", slog.Any("error", err))
+	if err := srv.Start(); err != nil {
+		logger.Error("server stopped with error", slog.Any("error", err))
+		os.Exit(1)
 	}
 }
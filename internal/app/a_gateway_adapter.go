@@ -2,6 +2,7 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,27 +14,45 @@ import (
 type GatewayA struct {
 	client   paymenthttp.HTTPClient
 	endpoint string
+	// apiKey authenticates the requests to the gateway, none when empty
+	apiKey string
+	// capabilities declares the currencies, transaction types, amounts and card brands the gateway supports
+	capabilities model.GatewayCapabilities
 }
 
 func newGatewayAAdapter(client paymenthttp.HTTPClient, endpoint string) *GatewayA {
+	// Gateway A does not deduplicate requests, so no idempotency key is sent and the attempts it may have
+	// processed are never retried
+	retryPolicy := paymenthttp.PaymentRetryPolicy{}
+
 	return &GatewayA{
-		client:   client,
+		client:   withRetryPolicy(client, retryPolicy),
 		endpoint: endpoint,
+		capabilities: model.GatewayCapabilities{
+			Currencies: []string{"USD", "EUR", "GBP"},
+			Types:      []model.TransactionType{model.Deposit, model.Withdrawal},
+			MinAmount:  1,
+			MaxAmount:  50000,
+			CardBrands: []model.CardBrand{model.Visa, model.Mastercard, model.Amex},
+		},
 	}
 }
 
-func (g *GatewayA) ProcessTransaction(tx model.Transaction) (model.GatewayResponse, error) {
+func (g *GatewayA) ProcessTransaction(ctx context.Context, tx model.Transaction) (model.GatewayResponse, error) {
 	jsonData, err := json.Marshal(g.buildGatewayRequest(tx))
 	if err != nil {
 		return model.GatewayResponse{}, fmt.Errorf("failed to marshal gateway request: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, g.endpoint+"/process", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.endpoint+"/process", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return model.GatewayResponse{}, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	req.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+	if g.apiKey != "" {
+		req.Header.Set(paymenthttp.HeaderAuthorization, "Bearer "+g.apiKey)
+	}
 
 	resp, err := g.client.Do(req)
 	if err != nil {
@@ -49,6 +68,34 @@ func (g *GatewayA) ProcessTransaction(tx model.Transaction) (model.GatewayRespon
 	return gr, nil
 }
 
+// Capabilities declares the transactions the gateway supports
+func (g *GatewayA) Capabilities() model.GatewayCapabilities {
+	return g.capabilities
+}
+
+// BreakerState returns the state of the gateway's own circuit breaker
+func (g *GatewayA) BreakerState() model.CircuitBreakerState {
+	return clientBreakerState(g.client)
+}
+
+// Retries returns the number of requests to the gateway retried by its client
+func (g *GatewayA) Retries() uint64 {
+	return clientRetries(g.client)
+}
+
+// CloseIdleConnections closes the idle connections to the gateway
+func (g *GatewayA) CloseIdleConnections() {
+	closeIdleConnections(g.client)
+}
+
+// withAPIKey returns a copy of the gateway sending the API key of a merchant, sharing its HTTP client
+func (g *GatewayA) withAPIKey(apiKey string) PaymentGateway {
+	clone := *g
+	clone.apiKey = apiKey
+
+	return &clone
+}
+
 func (g *GatewayA) buildGatewayRequest(tx model.Transaction) model.GatewayRequest {
 	return model.GatewayRequest{
 		OrderID:     tx.ID,
@@ -2,6 +2,7 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"net/http"
@@ -13,27 +14,44 @@ import (
 type GatewayB struct {
 	client   paymenthttp.HTTPClient
 	endpoint string
+	// apiKey authenticates the requests to the gateway, none when empty
+	apiKey string
+	// capabilities declares the currencies, transaction types, amounts and card brands the gateway supports
+	capabilities model.GatewayCapabilities
 }
 
 func newGatewayBAdapter(client paymenthttp.HTTPClient, endpoint string) *GatewayB {
+	// Gateway B does not deduplicate requests, so the attempts it may have processed are never retried
+	retryPolicy := paymenthttp.PaymentRetryPolicy{}
+
 	return &GatewayB{
-		client:   client,
+		client:   withRetryPolicy(client, retryPolicy),
 		endpoint: endpoint,
+		capabilities: model.GatewayCapabilities{
+			Currencies: []string{"USD", "EUR"},
+			Types:      []model.TransactionType{model.Deposit, model.Withdrawal},
+			MinAmount:  1,
+			MaxAmount:  20000,
+			CardBrands: []model.CardBrand{model.Visa, model.Mastercard, model.Discover},
+		},
 	}
 }
 
-func (g *GatewayB) ProcessTransaction(tx model.Transaction) (model.GatewayResponse, error) {
+func (g *GatewayB) ProcessTransaction(ctx context.Context, tx model.Transaction) (model.GatewayResponse, error) {
 	xmlData, err := xml.Marshal(g.buildGatewayRequest(tx))
 	if err != nil {
 		return model.GatewayResponse{}, fmt.Errorf("failed to marshal gateway request: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, g.endpoint+"/process", bytes.NewBuffer(xmlData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.endpoint+"/process", bytes.NewBuffer(xmlData))
 	if err != nil {
 		return model.GatewayResponse{}, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	req.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeXML)
+	if g.apiKey != "" {
+		req.Header.Set(paymenthttp.HeaderAuthorization, "Bearer "+g.apiKey)
+	}
 
 	resp, err := g.client.Do(req)
 	if err != nil {
@@ -53,6 +71,34 @@ func (g *GatewayB) ProcessTransaction(tx model.Transaction) (model.GatewayRespon
 	return gr, nil
 }
 
+// Capabilities declares the transactions the gateway supports
+func (g *GatewayB) Capabilities() model.GatewayCapabilities {
+	return g.capabilities
+}
+
+// BreakerState returns the state of the gateway's own circuit breaker
+func (g *GatewayB) BreakerState() model.CircuitBreakerState {
+	return clientBreakerState(g.client)
+}
+
+// Retries returns the number of requests to the gateway retried by its client
+func (g *GatewayB) Retries() uint64 {
+	return clientRetries(g.client)
+}
+
+// CloseIdleConnections closes the idle connections to the gateway
+func (g *GatewayB) CloseIdleConnections() {
+	closeIdleConnections(g.client)
+}
+
+// withAPIKey returns a copy of the gateway sending the API key of a merchant, sharing its HTTP client
+func (g *GatewayB) withAPIKey(apiKey string) PaymentGateway {
+	clone := *g
+	clone.apiKey = apiKey
+
+	return &clone
+}
+
 func (g *GatewayB) buildGatewayRequest(tx model.Transaction) model.GatewayRequest {
 	return model.GatewayRequest{
 		OrderID:     tx.ID,
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"sync"
+
+	"go-payment-service/pkg/model"
+)
+
+// subscriberBufferSize is the number of updates buffered for each subscriber
+const subscriberBufferSize = 8
+
+// transactionBroker is an in-process pub/sub delivering transaction status changes to subscribers
+type transactionBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan model.Transaction]struct{}
+	closed      bool
+}
+
+// newTransactionBroker creates a new transaction broker
+func newTransactionBroker() *transactionBroker {
+	return &transactionBroker{
+		subscribers: make(map[string]map[chan model.Transaction]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the transaction every time it is published and a function to unsubscribe.
+// The channel is closed on unsubscribe or when the broker is closed.
+func (b *transactionBroker) Subscribe(id string) (<-chan model.Transaction, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan model.Transaction, subscriberBufferSize)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if b.subscribers[id] == nil {
+		b.subscribers[id] = make(map[chan model.Transaction]struct{})
+	}
+	b.subscribers[id][ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, exists := b.subscribers[id][ch]; !exists {
+			return
+		}
+
+		delete(b.subscribers[id], ch)
+		if len(b.subscribers[id]) == 0 {
+			delete(b.subscribers, id)
+		}
+
+		close(ch)
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish sends the transaction to its subscribers without blocking.
+// Slow subscribers lose their oldest buffered update, as only the latest state matters.
+func (b *transactionBroker) Publish(tx model.Transaction) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[tx.ID] {
+		select {
+		case ch <- tx:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+
+			select {
+			case ch <- tx:
+			default:
+			}
+		}
+	}
+}
+
+// Close closes every subscription, releasing the subscribers
+func (b *transactionBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.closed = true
+
+	for id, channels := range b.subscribers {
+		for ch := range channels {
+			close(ch)
+		}
+
+		delete(b.subscribers, id)
+	}
+}
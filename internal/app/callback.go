@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"go-payment-service/internal/config"
+)
+
+// ErrCallbackUnauthenticated is returned when a status update does not carry the callback secret of the gateway
+// that processed the transaction
+var ErrCallbackUnauthenticated = errors.New("missing or invalid gateway callback secret")
+
+// callbackSecrets returns the callback secrets of the enabled gateways by ID, the gateways on the emulator
+// sharing its secret
+func callbackSecrets(gateways []config.Gateway, emulatorSecret string) map[string]string {
+	secrets := make(map[string]string, len(gateways))
+
+	for _, gw := range gateways {
+		switch {
+		case !gw.IsEnabled():
+		case gw.Endpoint == "":
+			secrets[gw.ID] = emulatorSecret
+		case gw.CallbackSecret != "":
+			secrets[gw.ID] = gw.CallbackSecret
+		}
+	}
+
+	return secrets
+}
+
+// validCallbackSecret reports whether the secret is the one of the gateway, never when the gateway has none
+func validCallbackSecret(secrets map[string]string, gatewayID, secret string) bool {
+	expected, exists := secrets[gatewayID]
+	return exists && expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(secret)) == 1
+}
+
+type callbackSecretKey struct{}
+
+// withCallbackSecret returns a copy of ctx carrying the secret sent with a gateway callback
+func withCallbackSecret(ctx context.Context, secret string) context.Context {
+	return context.WithValue(ctx, callbackSecretKey{}, secret)
+}
+
+// callbackSecretFromContext returns the secret sent with the gateway callback, empty when none was sent
+func callbackSecretFromContext(ctx context.Context) string {
+	secret, _ := ctx.Value(callbackSecretKey{}).(string)
+	return secret
+}
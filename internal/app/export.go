@@ -0,0 +1,233 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+)
+
+// exportFlushEvery is the number of records written before the response is flushed to the client
+const exportFlushEvery = 100
+
+var exportCSVHeader = []string{
+	"id", "externalId", "type", "status", "amount", "currency",
+	"cardName", "cardNumber", "cardType", "gatewayId", "createdAt", "updatedAt",
+}
+
+// exportWriter writes transactions one at a time in a given format
+type exportWriter interface {
+	Write(tx model.Transaction) error
+	Flush() error
+}
+
+func (h *handler) exportTransactions(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get(paymenthttp.HeaderContentType)
+
+	// negotiate export format
+	format, ok := negotiateExportFormat(r.Header.Get(paymenthttp.HeaderAccept))
+	if !ok {
+		h.errorResponse(w, contentType, http.StatusNotAcceptable, "supported formats are text/csv and application/x-ndjson")
+		return
+	}
+
+	// parse filters
+	filter, err := parseExportFilter(r)
+	if err != nil {
+		slog.DebugContext(r.Context(), "failed to parse export filter", slog.Any("error", err))
+		h.errorResponse(w, contentType, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set(paymenthttp.HeaderContentType, format)
+
+	var ew exportWriter
+	if format == paymenthttp.MIMETypeCSV {
+		w.Header().Set(paymenthttp.HeaderContentDisposition, `attachment; filename="transactions.csv"`)
+		ew = newCSVExportWriter(w)
+	} else {
+		ew = newNDJSONExportWriter(w)
+	}
+
+	rc := http.NewResponseController(w)
+	clearDeadlines(rc)
+
+	// stream transactions
+	count := 0
+	err = h.service.Export(r.Context(), filter, func(tx model.Transaction) error {
+		if err := ew.Write(tx); err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := ew.Flush(); err != nil {
+				return err
+			}
+
+			return rc.Flush()
+		}
+
+		return nil
+	})
+	if err == nil {
+		err = ew.Flush()
+	}
+
+	// the response has already started, so the export can only be aborted
+	if err != nil {
+		slog.DebugContext(r.Context(), "failed to export transactions", slog.Any("error", err), slog.Int("exported", count))
+		return
+	}
+}
+
+// negotiateExportFormat returns the first export format accepted by the client, defaulting to CSV
+func negotiateExportFormat(accept string) (string, bool) {
+	if accept == "" {
+		return paymenthttp.MIMETypeCSV, true
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case paymenthttp.MIMETypeCSV, "*/*", "text/*":
+			return paymenthttp.MIMETypeCSV, true
+		case paymenthttp.MIMETypeNDJSON:
+			return paymenthttp.MIMETypeNDJSON, true
+		}
+	}
+
+	return "", false
+}
+
+// parseExportFilter reads the filters from the query string:
+// from and to (RFC 3339 timestamps or dates, to being inclusive for dates), status and gateway
+func parseExportFilter(r *http.Request) (TransactionFilter, error) {
+	query := r.URL.Query()
+
+	filter := TransactionFilter{
+		Status:    model.TransactionStatus(query.Get("status")),
+		GatewayID: query.Get("gateway"),
+	}
+
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return TransactionFilter{}, fmt.Errorf("invalid status %q: must be one of %s, %s, %s or %s",
+			filter.Status, model.Pending, model.Processing, model.Succeeded, model.Failed)
+	}
+
+	if from := query.Get("from"); from != "" {
+		t, _, err := parseExportTime(from)
+		if err != nil {
+			return TransactionFilter{}, fmt.Errorf("invalid from: %w", err)
+		}
+
+		filter.From = t
+	}
+
+	if to := query.Get("to"); to != "" {
+		t, isDate, err := parseExportTime(to)
+		if err != nil {
+			return TransactionFilter{}, fmt.Errorf("invalid to: %w", err)
+		}
+
+		// include the whole day
+		if isDate {
+			t = t.AddDate(0, 0, 1)
+		}
+
+		filter.To = t
+	}
+
+	return filter, nil
+}
+
+func parseExportTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, errors.New("expected a date (2006-01-02) or an RFC 3339 timestamp")
+	}
+
+	return t, false, nil
+}
+
+type csvExportWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func newCSVExportWriter(w http.ResponseWriter) *csvExportWriter {
+	return &csvExportWriter{w: csv.NewWriter(w)}
+}
+
+func (cw *csvExportWriter) Write(tx model.Transaction) error {
+	if !cw.headerWritten {
+		if err := cw.w.Write(exportCSVHeader); err != nil {
+			return err
+		}
+
+		cw.headerWritten = true
+	}
+
+	return cw.w.Write([]string{
+		tx.ID,
+		tx.ExternalID,
+		string(tx.Type),
+		string(tx.Status),
+		strconv.FormatFloat(tx.Amount.Amount, 'f', -1, 64),
+		tx.Amount.Currency,
+		tx.CardDetails.Name,
+		tx.CardDetails.Number,
+		tx.CardDetails.Type,
+		tx.GatewayDetails.ID,
+		tx.CreatedAt.Format(time.RFC3339),
+		tx.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+func (cw *csvExportWriter) Flush() error {
+	// always write the header, even when there are no transactions
+	if !cw.headerWritten {
+		if err := cw.w.Write(exportCSVHeader); err != nil {
+			return err
+		}
+
+		cw.headerWritten = true
+	}
+
+	cw.w.Flush()
+
+	return cw.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+}
+
+func newNDJSONExportWriter(w http.ResponseWriter) *ndjsonExportWriter {
+	return &ndjsonExportWriter{enc: json.NewEncoder(w)}
+}
+
+func (nw *ndjsonExportWriter) Write(tx model.Transaction) error {
+	return nw.enc.Encode(tx)
+}
+
+func (nw *ndjsonExportWriter) Flush() error {
+	return nil
+}
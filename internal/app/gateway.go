@@ -1,9 +1,109 @@
 package app
 
-import "go-payment-service/pkg/model"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+)
+
+// ErrNotSupported is returned when a gateway cannot process a transaction
+var ErrNotSupported = errors.New("transaction not supported by gateway")
 
 // PaymentGateway represents an extensible payment gateway interface (protocol-agnostic)
-// that can process transactions
+// that can process transactions. Implementations must stop processing once the context is done.
 type PaymentGateway interface {
-	ProcessTransaction(tx model.Transaction) (model.GatewayResponse, error)
+	ProcessTransaction(ctx context.Context, tx model.Transaction) (model.GatewayResponse, error)
+	// Capabilities declares the transactions the gateway supports, checked before they are created
+	Capabilities() model.GatewayCapabilities
+}
+
+// checkCapabilities returns an error describing the first transaction attribute the gateway does not support
+func checkCapabilities(id string, caps model.GatewayCapabilities, attrs model.RoutingAttributes) error {
+	switch {
+	case len(caps.Currencies) > 0 && !slices.ContainsFunc(caps.Currencies, func(c string) bool { return strings.EqualFold(c, attrs.Currency) }):
+		return fmt.Errorf("%w: %s does not support currency %s", ErrNotSupported, id, attrs.Currency)
+	case len(caps.Types) > 0 && !slices.Contains(caps.Types, attrs.Type):
+		return fmt.Errorf("%w: %s does not support %s transactions", ErrNotSupported, id, attrs.Type)
+	case attrs.Amount < caps.MinAmount:
+		return fmt.Errorf("%w: %s requires an amount of at least %g", ErrNotSupported, id, caps.MinAmount)
+	case caps.MaxAmount != 0 && attrs.Amount > caps.MaxAmount:
+		return fmt.Errorf("%w: %s requires an amount of at most %g", ErrNotSupported, id, caps.MaxAmount)
+	case len(caps.CardBrands) > 0 && !slices.Contains(caps.CardBrands, attrs.CardBrand):
+		return fmt.Errorf("%w: %s does not support %s cards", ErrNotSupported, id, attrs.CardBrand)
+	default:
+		return nil
+	}
+}
+
+// breakerReporter is implemented by the gateways exposing their circuit breaker state
+type breakerReporter interface {
+	BreakerState() model.CircuitBreakerState
+}
+
+// clientBreakerReporter is implemented by the HTTP clients exposing their circuit breaker state
+type clientBreakerReporter interface {
+	BreakerState() paymenthttp.BreakerState
+}
+
+// clientBreakerState returns the circuit breaker state of the client, if it has one
+func clientBreakerState(client paymenthttp.HTTPClient) model.CircuitBreakerState {
+	if r, ok := client.(clientBreakerReporter); ok {
+		state := r.BreakerState()
+
+		return model.CircuitBreakerState{
+			State:                state.State,
+			Requests:             state.Requests,
+			TotalSuccesses:       state.TotalSuccesses,
+			TotalFailures:        state.TotalFailures,
+			ConsecutiveSuccesses: state.ConsecutiveSuccesses,
+			ConsecutiveFailures:  state.ConsecutiveFailures,
+		}
+	}
+
+	return model.CircuitBreakerState{State: "unknown"}
+}
+
+// retryPolicyClient is implemented by the HTTP clients retrying the failed attempts
+type retryPolicyClient interface {
+	WithRetryPolicy(policy paymenthttp.RetryPolicy) *paymenthttp.ResilientHTTPClient
+}
+
+// withRetryPolicy gives the retry policy of a gateway to its HTTP client, when the client retries
+func withRetryPolicy(client paymenthttp.HTTPClient, policy paymenthttp.RetryPolicy) paymenthttp.HTTPClient {
+	if c, ok := client.(retryPolicyClient); ok {
+		return c.WithRetryPolicy(policy)
+	}
+
+	return client
+}
+
+// retryReporter is implemented by the gateways and HTTP clients counting their retries
+type retryReporter interface {
+	Retries() uint64
+}
+
+// clientRetries returns the number of attempts retried by the client, zero when it does not count them
+func clientRetries(client paymenthttp.HTTPClient) uint64 {
+	if r, ok := client.(retryReporter); ok {
+		return r.Retries()
+	}
+
+	return 0
+}
+
+// idleCloser is implemented by the gateways and HTTP clients holding a connection pool
+type idleCloser interface {
+	CloseIdleConnections()
+}
+
+// closeIdleConnections closes the idle connections of the gateway or client, if it holds some
+func closeIdleConnections(v any) {
+	if c, ok := v.(idleCloser); ok {
+		c.CloseIdleConnections()
+	}
 }
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go-payment-service/internal/config"
+	paymenthttp "go-payment-service/pkg/http"
+)
+
+// gatewayFactory creates the adapter of a gateway kind from its HTTP client and configuration
+type gatewayFactory func(client paymenthttp.HTTPClient, cfg config.Gateway) PaymentGateway
+
+// gatewayFactories registers the adapter kinds gateways can be configured with
+var gatewayFactories = map[string]gatewayFactory{
+	"gatewayA": func(client paymenthttp.HTTPClient, cfg config.Gateway) PaymentGateway {
+		g := newGatewayAAdapter(client, cfg.Endpoint)
+		g.apiKey = cfg.APIKey
+		return g
+	},
+	"gatewayB": func(client paymenthttp.HTTPClient, cfg config.Gateway) PaymentGateway {
+		g := newGatewayBAdapter(client, cfg.Endpoint)
+		g.apiKey = cfg.APIKey
+		return g
+	},
+}
+
+// newGateways creates the adapters of the enabled gateways, each with its own HTTP client so an outage of one
+// does not trip the others. emulatorURL is called for the gateways without endpoint, only when there are some.
+func newGateways(gateways []config.Gateway, emulatorURL func() string) (map[string]PaymentGateway, error) {
+	adapters := make(map[string]PaymentGateway, len(gateways))
+
+	for _, gw := range gateways {
+		if !gw.IsEnabled() {
+			continue
+		}
+
+		adapter, err := newGateway(gw, emulatorURL)
+		if err != nil {
+			return nil, err
+		}
+
+		adapters[gw.ID] = adapter
+	}
+
+	return adapters, nil
+}
+
+// newGateway creates the adapter of the gateway with its own HTTP client
+func newGateway(gw config.Gateway, emulatorURL func() string) (PaymentGateway, error) {
+	factory, exists := gatewayFactories[gw.Kind]
+	if !exists {
+		return nil, fmt.Errorf("gateway %s: unknown kind %q, expected one of %v", gw.ID, gw.Kind, gatewayKinds())
+	}
+
+	if gw.Endpoint == "" {
+		gw.Endpoint = emulatorURL()
+	}
+
+	return factory(paymenthttp.NewResilientHTTPClientWithConfig(gatewayClientConfig(gw)), gw), nil
+}
+
+// gatewayIDs returns the IDs of the configured gateways, enabled or not
+func gatewayIDs(gateways []config.Gateway) []string {
+	ids := make([]string, 0, len(gateways))
+	for _, gw := range gateways {
+		ids = append(ids, gw.ID)
+	}
+
+	return ids
+}
+
+// gatewayClientConfig returns the HTTP client configuration of the gateway, overriding the defaults with its settings
+func gatewayClientConfig(gw config.Gateway) paymenthttp.ClientConfig {
+	cfg := paymenthttp.DefaultClientConfig(gw.ID)
+
+	if gw.Timeout != 0 {
+		cfg.Timeout = time.Duration(gw.Timeout)
+	}
+	if gw.MaxRetries != nil {
+		cfg.MaxRetries = *gw.MaxRetries
+	}
+	if gw.BreakerFailures != 0 {
+		cfg.BreakerFailures = gw.BreakerFailures
+	}
+	if gw.BreakerTimeout != 0 {
+		cfg.BreakerTimeout = time.Duration(gw.BreakerTimeout)
+	}
+	if gw.MaxConns != 0 {
+		cfg.MaxConnsPerHost = gw.MaxConns
+	}
+
+	return cfg
+}
+
+// gatewayKinds returns the registered adapter kinds, sorted
+func gatewayKinds() []string {
+	kinds := make([]string, 0, len(gatewayFactories))
+	for kind := range gatewayFactories {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	return kinds
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"go-payment-service/internal/config"
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+)
+
+type TestGatewayRegistrySuite struct {
+	suite.Suite
+}
+
+func (suite *TestGatewayRegistrySuite) TestNewGateways() {
+	var authorization string
+
+	gatewayServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get(paymenthttp.HeaderAuthorization)
+		_ = json.NewEncoder(w).Encode(model.GatewayResponse{Status: model.Pending})
+	}))
+	defer gatewayServer.Close()
+
+	disabled := false
+	gateways := []config.Gateway{
+		{ID: "live", Kind: "gatewayA", Endpoint: gatewayServer.URL, APIKey: "secret"},
+		{ID: "off", Kind: "gatewayB", Enabled: &disabled},
+	}
+
+	emulatorStarted := false
+	adapters, err := newGateways(gateways, func() string {
+		emulatorStarted = true
+		return "http://localhost:0"
+	})
+	suite.Require().NoError(err)
+
+	suite.Len(adapters, 1)
+	suite.False(emulatorStarted, "the emulator is not needed when every enabled gateway has an endpoint")
+
+	_, err = adapters["live"].ProcessTransaction(context.Background(), model.Transaction{ID: "1"})
+	suite.Require().NoError(err)
+	suite.Equal("Bearer secret", authorization)
+
+	_, err = newGateways([]config.Gateway{{ID: "gatewayC", Kind: "gatewayC"}}, nil)
+	suite.ErrorContains(err, `gateway gatewayC: unknown kind "gatewayC", expected one of [gatewayA gatewayB]`)
+}
+
+func (suite *TestGatewayRegistrySuite) TestGatewayClientConfig() {
+	retries := uint64(0)
+	cfg := gatewayClientConfig(config.Gateway{ID: "gatewayA", Timeout: config.Duration(5 * time.Second), MaxRetries: &retries})
+
+	suite.Equal(5*time.Second, cfg.Timeout)
+	suite.Equal(uint64(0), cfg.MaxRetries)
+	suite.Equal(paymenthttp.DefaultClientConfig("gatewayA").BreakerTimeout, cfg.BreakerTimeout)
+}
+
+func TestTestGatewayRegistrySuite(t *testing.T) {
+	suite.Run(t, new(TestGatewayRegistrySuite))
+}
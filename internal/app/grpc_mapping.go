@@ -0,0 +1,111 @@
+package app
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"go-payment-service/pkg/model"
+	paymentv1 "go-payment-service/pkg/pb/payment/v1"
+)
+
+var (
+	protoStatuses = map[model.TransactionStatus]paymentv1.TransactionStatus{
+		model.Pending:    paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING,
+		model.Processing: paymentv1.TransactionStatus_TRANSACTION_STATUS_PROCESSING,
+		model.Succeeded:  paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED,
+		model.Failed:     paymentv1.TransactionStatus_TRANSACTION_STATUS_FAILED,
+	}
+
+	protoTypes = map[model.TransactionType]paymentv1.TransactionType{
+		model.Deposit:    paymentv1.TransactionType_TRANSACTION_TYPE_DEPOSIT,
+		model.Withdrawal: paymentv1.TransactionType_TRANSACTION_TYPE_WITHDRAWAL,
+	}
+)
+
+func toModelBaseRequest(amount *paymentv1.Money, card *paymentv1.CardDetails, gateway *paymentv1.GatewayDetails) model.BaseRequest {
+	return model.BaseRequest{
+		Amount: model.Money{
+			Amount:   amount.GetAmount(),
+			Currency: amount.GetCurrency(),
+		},
+		CardDetails: model.CardDetails{
+			Name:        card.GetName(),
+			Number:      card.GetNumber(),
+			Type:        card.GetType(),
+			ExpiryMonth: int(card.GetExpiryMonth()),
+			ExpiryYear:  int(card.GetExpiryYear()),
+			CVV:         card.GetCvv(),
+		},
+		GatewayDetails: model.GatewayDetails{
+			ID:          gateway.GetId(),
+			Name:        gateway.GetName(),
+			CallbackURL: gateway.GetCallbackUrl(),
+			Fallback:    gateway.GetFallback(),
+		},
+	}
+}
+
+func toModelStatus(s paymentv1.TransactionStatus) model.TransactionStatus {
+	for status, protoStatus := range protoStatuses {
+		if protoStatus == s {
+			return status
+		}
+	}
+
+	return ""
+}
+
+func toProtoTransactionResponse(res model.GatewayResponse) *paymentv1.TransactionResponse {
+	return &paymentv1.TransactionResponse{
+		TransactionId: res.TransactionID,
+		Message:       res.Message,
+		Status:        protoStatuses[res.Status],
+		ProcessedAt:   timestamppb.New(res.ProcessedAt),
+	}
+}
+
+// toProtoTransaction maps the transaction with its card details masked, the full number and CVV are never sent
+func toProtoTransaction(tx model.Transaction) *paymentv1.Transaction {
+	tx = tx.Masked()
+
+	return &paymentv1.Transaction{
+		Id: tx.ID,
+		Amount: &paymentv1.Money{
+			Amount:   tx.Amount.Amount,
+			Currency: tx.Amount.Currency,
+		},
+		CardDetails: &paymentv1.CardDetails{
+			Name:        tx.CardDetails.Name,
+			Number:      tx.CardDetails.Number,
+			Type:        tx.CardDetails.Type,
+			ExpiryMonth: int32(tx.CardDetails.ExpiryMonth),
+			ExpiryYear:  int32(tx.CardDetails.ExpiryYear),
+			Cvv:         tx.CardDetails.CVV,
+		},
+		GatewayDetails: &paymentv1.GatewayDetails{
+			Id:          tx.GatewayDetails.ID,
+			Name:        tx.GatewayDetails.Name,
+			CallbackUrl: tx.GatewayDetails.CallbackURL,
+			Fallback:    tx.GatewayDetails.Fallback,
+		},
+		Type:       protoTypes[tx.Type],
+		Status:     protoStatuses[tx.Status],
+		ExternalId: tx.ExternalID,
+		CreatedAt:  timestamppb.New(tx.CreatedAt),
+		UpdatedAt:  timestamppb.New(tx.UpdatedAt),
+		Routing:    toProtoRouting(tx.Routing),
+	}
+}
+
+func toProtoRouting(attempts []model.RoutingAttempt) []*paymentv1.RoutingAttempt {
+	routing := make([]*paymentv1.RoutingAttempt, 0, len(attempts))
+	for _, attempt := range attempts {
+		routing = append(routing, &paymentv1.RoutingAttempt{
+			GatewayId:   attempt.GatewayID,
+			Outcome:     string(attempt.Outcome),
+			Reason:      attempt.Reason,
+			AttemptedAt: timestamppb.New(attempt.AttemptedAt),
+		})
+	}
+
+	return routing
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+	paymentv1 "go-payment-service/pkg/pb/payment/v1"
+)
+
+// grpcTransactionServer exposes the TransactionService over gRPC
+type grpcTransactionServer struct {
+	paymentv1.UnimplementedTransactionServiceServer
+	service  TransactionService
+	validate *validator.Validate
+}
+
+// newGRPCServer creates the gRPC server, the calls are authenticated unless merchants is nil
+func newGRPCServer(service TransactionService, merchants *merchantAuthenticator) *grpc.Server {
+	var opts []grpc.ServerOption
+	if merchants != nil {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(merchants.UnaryInterceptor),
+			grpc.ChainStreamInterceptor(merchants.StreamInterceptor),
+		)
+	}
+
+	srv := grpc.NewServer(opts...)
+
+	paymentv1.RegisterTransactionServiceServer(srv, &grpcTransactionServer{
+		service:  service,
+		validate: paymenthttp.NewValidator(),
+	})
+
+	return srv
+}
+
+func (s *grpcTransactionServer) Deposit(ctx context.Context, in *paymentv1.DepositRequest) (*paymentv1.TransactionResponse, error) {
+	req := model.DepositRequest{
+		BaseRequest: toModelBaseRequest(in.GetAmount(), in.GetCardDetails(), in.GetGatewayDetails()),
+	}
+
+	// validate request
+	if err := s.validate.Struct(req); err != nil {
+		slog.DebugContext(ctx, "grpc: failed to validate deposit request", slog.Any("error", err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// process request
+	res, err := s.service.Deposit(ctx, req)
+	if err != nil {
+		slog.DebugContext(ctx, "grpc: failed to process deposit", slog.Any("error", err))
+		return nil, toGRPCError(err)
+	}
+
+	return toProtoTransactionResponse(res.GatewayResponse), nil
+}
+
+func (s *grpcTransactionServer) Withdrawal(ctx context.Context, in *paymentv1.WithdrawalRequest) (*paymentv1.TransactionResponse, error) {
+	req := model.WithdrawalRequest{
+		BaseRequest: toModelBaseRequest(in.GetAmount(), in.GetCardDetails(), in.GetGatewayDetails()),
+	}
+
+	// validate request
+	if err := s.validate.Struct(req); err != nil {
+		slog.DebugContext(ctx, "grpc: failed to validate withdrawal request", slog.Any("error", err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// process request
+	res, err := s.service.Withdrawal(ctx, req)
+	if err != nil {
+		slog.DebugContext(ctx, "grpc: failed to process withdrawal", slog.Any("error", err))
+		return nil, toGRPCError(err)
+	}
+
+	return toProtoTransactionResponse(res.GatewayResponse), nil
+}
+
+func (s *grpcTransactionServer) GetByID(ctx context.Context, in *paymentv1.GetByIDRequest) (*paymentv1.Transaction, error) {
+	tx, err := s.service.GetByID(ctx, in.GetId())
+	if err != nil {
+		slog.DebugContext(ctx, "grpc: failed to get transaction", slog.Any("error", err))
+		return nil, toGRPCError(err)
+	}
+
+	return toProtoTransaction(*tx), nil
+}
+
+func (s *grpcTransactionServer) UpdateStatus(ctx context.Context, in *paymentv1.UpdateStatusRequest) (*paymentv1.UpdateStatusResponse, error) {
+	req := model.TransactionStatusUpdate{
+		TransactionID: in.GetTransactionId(),
+		Status:        toModelStatus(in.GetStatus()),
+		ReceivedAt:    time.Now(),
+		Details:       in.GetDetails(),
+	}
+
+	if req.TransactionID == "" || req.Status == "" {
+		return nil, status.Error(codes.InvalidArgument, "transaction id and status are required")
+	}
+
+	// authenticated with the callback secret of the gateway in the authorization metadata, e.g. "Bearer <secret>"
+	if err := s.service.UpdateStatus(withCallbackSecret(ctx, bearerToken(authorizationMetadata(ctx))), req); err != nil {
+		slog.DebugContext(ctx, "grpc: failed to update transaction status", slog.Any("error", err))
+		return nil, toGRPCError(err)
+	}
+
+	return &paymentv1.UpdateStatusResponse{}, nil
+}
+
+func (s *grpcTransactionServer) WatchTransaction(in *paymentv1.WatchTransactionRequest, stream grpc.ServerStreamingServer[paymentv1.Transaction]) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	updates, err := s.service.Watch(ctx, in.GetId())
+	if err != nil {
+		slog.DebugContext(ctx, "grpc: failed to watch transaction", slog.Any("error", err))
+		return toGRPCError(err)
+	}
+
+	for tx := range updates {
+		if err := stream.Send(toProtoTransaction(tx)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func toGRPCError(err error) error {
+	if errors.Is(err, ErrTransactionNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	if errors.Is(err, ErrCallbackUnauthenticated) {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if errors.Is(err, ErrUnsupportedGateway) || errors.Is(err, ErrNotSupported) || errors.Is(err, ErrInvalidCallbackURL) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
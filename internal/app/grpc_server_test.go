@@ -0,0 +1,229 @@
+package app
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+	paymentv1 "go-payment-service/pkg/pb/payment/v1"
+	"go-payment-service/test/emulator"
+)
+
+type TestGRPCServerSuite struct {
+	suite.Suite
+	server     *grpc.Server
+	conn       *grpc.ClientConn
+	client     paymentv1.TransactionServiceClient
+	repository TransactionRepository
+}
+
+// SetupSuite runs before all tests
+func (suite *TestGRPCServerSuite) SetupSuite() {
+	wg := &sync.WaitGroup{}
+
+	// Initialize payment gateways
+	gatewayEmulator := emulator.StartWithCallbackSecret(testCallbackSecret)
+
+	gateways := map[string]PaymentGateway{
+		"gatewayA": newGatewayAAdapter(paymenthttp.NewResilientHTTPClientWithConfig(paymenthttp.DefaultClientConfig("gatewayA")), gatewayEmulator.URL),
+		"gatewayB": newGatewayBAdapter(paymenthttp.NewResilientHTTPClientWithConfig(paymenthttp.DefaultClientConfig("gatewayB")), gatewayEmulator.URL),
+	}
+
+	repository := newMemoryTransactionRepository()
+	suite.repository = repository
+	webhooks := newWebhookService(newMemoryWebhookRepository(), []byte("secret"))
+	router := newGatewayRouter(gateways, nil, "gatewayA", "gatewayB")
+	router.setCallbackSecrets(map[string]string{"gatewayA": testCallbackSecret, "gatewayB": testCallbackSecret})
+	service := newTransactionService(wg, router, repository, newTransactionBroker(), webhooks)
+
+	lis := bufconn.Listen(1024 * 1024)
+	suite.server = newGRPCServer(service, nil)
+
+	go func() {
+		_ = suite.server.Serve(lis)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	suite.Require().NoError(err)
+
+	suite.conn = conn
+	suite.client = paymentv1.NewTransactionServiceClient(conn)
+}
+
+// TearDownSuite runs after all tests
+func (suite *TestGRPCServerSuite) TearDownSuite() {
+	suite.conn.Close()
+	suite.server.Stop()
+}
+
+func (suite *TestGRPCServerSuite) TestDeposit() {
+	testCases := []struct {
+		name         string
+		given        *paymentv1.DepositRequest
+		expected     paymentv1.TransactionStatus
+		expectedCode codes.Code
+	}{
+		{
+			name: "pending",
+			given: &paymentv1.DepositRequest{
+				Amount: &paymentv1.Money{Amount: 1000, Currency: "USD"},
+				CardDetails: &paymentv1.CardDetails{
+					Number:      "4111111111111111",
+					Name:        "John Doe",
+					ExpiryMonth: 12,
+					ExpiryYear:  2023,
+					Cvv:         "123",
+				},
+				GatewayDetails: &paymentv1.GatewayDetails{Id: "gatewayA"},
+			},
+			expected:     paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING,
+			expectedCode: codes.OK,
+		},
+		{
+			name: "invalid currency",
+			given: &paymentv1.DepositRequest{
+				Amount: &paymentv1.Money{Amount: 1000, Currency: "XYZ"},
+				CardDetails: &paymentv1.CardDetails{
+					Number:      "4111111111111111",
+					Name:        "John Doe",
+					ExpiryMonth: 12,
+					ExpiryYear:  2023,
+					Cvv:         "123",
+				},
+				GatewayDetails: &paymentv1.GatewayDetails{Id: "gatewayA"},
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			resp, err := suite.client.Deposit(context.Background(), tc.given)
+
+			suite.Equal(tc.expectedCode, status.Code(err))
+			if tc.expectedCode != codes.OK {
+				return
+			}
+
+			suite.NotEmpty(resp.GetTransactionId())
+			suite.Equal(tc.expected, resp.GetStatus())
+		})
+	}
+}
+
+func (suite *TestGRPCServerSuite) TestGetByIDMasksCard() {
+	resp, err := suite.client.Deposit(context.Background(), &paymentv1.DepositRequest{
+		Amount: &paymentv1.Money{Amount: 1000, Currency: "USD"},
+		CardDetails: &paymentv1.CardDetails{
+			Number:      "4111111111111111",
+			Name:        "John Doe",
+			ExpiryMonth: 12,
+			ExpiryYear:  2023,
+			Cvv:         "123",
+		},
+		GatewayDetails: &paymentv1.GatewayDetails{Id: "gatewayA"},
+	})
+	suite.Require().NoError(err)
+
+	tx, err := suite.client.GetByID(context.Background(), &paymentv1.GetByIDRequest{Id: resp.GetTransactionId()})
+	suite.Require().NoError(err)
+
+	suite.Equal("411111******1111", tx.GetCardDetails().GetNumber())
+	suite.Empty(tx.GetCardDetails().GetCvv())
+}
+
+func (suite *TestGRPCServerSuite) TestGetByIDNotFound() {
+	_, err := suite.client.GetByID(context.Background(), &paymentv1.GetByIDRequest{Id: "unknown"})
+
+	suite.Equal(codes.NotFound, status.Code(err))
+}
+
+func (suite *TestGRPCServerSuite) TestUpdateStatus() {
+	tx := model.Transaction{ID: "grpc-callback-tx", ExternalID: "grpc-callback-external-tx", Status: model.Pending, GatewayDetails: model.GatewayDetails{ID: "gatewayA"}}
+	suite.Require().NoError(suite.repository.Create(&tx))
+
+	testCases := []struct {
+		name          string
+		authorization string
+		expectedCode  codes.Code
+	}{
+		{name: "missing secret", expectedCode: codes.Unauthenticated},
+		{name: "other secret", authorization: "Bearer other", expectedCode: codes.Unauthenticated},
+		{name: "callback secret of the gateway", authorization: "Bearer " + testCallbackSecret, expectedCode: codes.OK},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tc.authorization)
+			}
+
+			_, err := suite.client.UpdateStatus(ctx, &paymentv1.UpdateStatusRequest{
+				TransactionId: tx.ExternalID,
+				Status:        paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED,
+			})
+
+			suite.Equal(tc.expectedCode, status.Code(err))
+		})
+	}
+
+	updated, err := suite.repository.GetByID(tx.ID)
+	suite.Require().NoError(err)
+	suite.Equal(model.Succeeded, updated.Status)
+}
+
+func (suite *TestGRPCServerSuite) TestWatchTransaction() {
+	resp, err := suite.client.Withdrawal(context.Background(), &paymentv1.WithdrawalRequest{
+		Amount: &paymentv1.Money{Amount: 1000, Currency: "USD"},
+		CardDetails: &paymentv1.CardDetails{
+			Number:      "4111111111111111",
+			Name:        "John Doe",
+			ExpiryMonth: 12,
+			ExpiryYear:  2023,
+			Cvv:         "123",
+		},
+		GatewayDetails: &paymentv1.GatewayDetails{Id: "gatewayB"},
+	})
+	suite.Require().NoError(err)
+
+	stream, err := suite.client.WatchTransaction(context.Background(), &paymentv1.WatchTransactionRequest{
+		Id: resp.GetTransactionId(),
+	})
+	suite.Require().NoError(err)
+
+	var last *paymentv1.Transaction
+	for {
+		tx, err := stream.Recv()
+		if err != nil {
+			break
+		}
+
+		last = tx
+	}
+
+	suite.Require().NotNil(last)
+	suite.Equal(paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED, last.GetStatus())
+	suite.Equal("411111******1111", last.GetCardDetails().GetNumber())
+	suite.Empty(last.GetCardDetails().GetCvv())
+}
+
+func TestTestGRPCServerSuite(t *testing.T) {
+	suite.Run(t, new(TestGRPCServerSuite))
+}
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 
@@ -13,13 +15,17 @@ import (
 type handler struct {
 	mux      *http.ServeMux
 	service  TransactionService
+	webhooks WebhookService
+	health   *healthChecker
 	validate *validator.Validate
 }
 
-func newHandler(service TransactionService) *handler {
+func newHandler(service TransactionService, webhooks WebhookService, health *healthChecker) *handler {
 	h := handler{
 		service:  service,
-		validate: validator.New(),
+		webhooks: webhooks,
+		health:   health,
+		validate: paymenthttp.NewValidator(),
 	}
 
 	h.registerRoutes()
@@ -27,6 +33,9 @@ func newHandler(service TransactionService) *handler {
 	return &h
 }
 
+// publicRoutes are served without merchant authentication: the gateway callbacks, the probes and the metrics
+var publicRoutes = []string{"POST /callback", "GET /healthz", "GET /readyz", "GET /metrics"}
+
 func (h *handler) registerRoutes() {
 	// Initialize HTTP request multiplexer
 	mux := http.NewServeMux()
@@ -35,7 +44,21 @@ func (h *handler) registerRoutes() {
 	mux.HandleFunc("POST /deposit", h.deposit)
 	mux.HandleFunc("POST /withdrawal", h.withdrawal)
 	mux.HandleFunc("POST /callback", h.callback)
+	mux.HandleFunc("GET /transactions/export", h.exportTransactions)
 	mux.HandleFunc("GET /transactions/{id}", h.getTransaction)
+	mux.HandleFunc("GET /transactions/{id}/stream", h.streamTransaction)
+	mux.HandleFunc("GET /gateways", h.listGateways)
+	mux.HandleFunc("POST /routing/dry-run", h.dryRunRoute)
+
+	// Admin routes
+	mux.HandleFunc("GET /admin/webhooks/dead-letters", h.listDeadLetters)
+	mux.HandleFunc("POST /admin/webhooks/{id}/replay", h.replayWebhook)
+	mux.HandleFunc("GET /admin/routing/weights", h.getWeights)
+	mux.HandleFunc("PUT /admin/routing/weights", h.setWeights)
+
+	// Probes
+	mux.HandleFunc("GET /healthz", h.liveness)
+	mux.HandleFunc("GET /readyz", h.readiness)
 
 	h.mux = mux
 }
@@ -46,30 +69,30 @@ func (h *handler) deposit(w http.ResponseWriter, r *http.Request) {
 	// decode request
 	var req model.DepositRequest
 	if err := paymenthttp.Decode(r.Body, contentType, &req); err != nil {
-		slog.Debug("failed to decode deposit request", slog.Any("error", err))
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		slog.DebugContext(r.Context(), "failed to decode deposit request", slog.Any("error", err))
+		h.badRequestResponse(w, contentType, err)
 		return
 	}
 
 	// validate request
 	if err := h.validate.Struct(req); err != nil {
-		slog.Debug("failed to validate deposit request", slog.Any("error", err))
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		slog.DebugContext(r.Context(), "failed to validate deposit request", slog.Any("error", err))
+		h.badRequestResponse(w, contentType, err)
 		return
 	}
 
 	// process request
 	res, err := h.service.Deposit(r.Context(), req)
 	if err != nil {
-		slog.Debug("failed to process deposit", slog.Any("error", err))
-		h.errorResponse(w, contentType, http.StatusInternalServerError, err.Error())
+		slog.DebugContext(r.Context(), "failed to process deposit", slog.Any("error", err))
+		h.serviceErrorResponse(w, r, contentType, err)
 		return
 	}
 
 	// encode response
 	if err := paymenthttp.Encode(w, contentType, res); err != nil {
-		slog.Debug("failed to encode deposit response", slog.Any("error", err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		slog.DebugContext(r.Context(), "failed to encode deposit response", slog.Any("error", err))
+		h.internalErrorResponse(w, contentType)
 		return
 	}
 }
@@ -80,30 +103,30 @@ func (h *handler) withdrawal(w http.ResponseWriter, r *http.Request) {
 	// decode request
 	var req model.WithdrawalRequest
 	if err := paymenthttp.Decode(r.Body, contentType, &req); err != nil {
-		slog.Debug("failed to decode withdrawal request", slog.Any("error", err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		slog.DebugContext(r.Context(), "failed to decode withdrawal request", slog.Any("error", err))
+		h.badRequestResponse(w, contentType, err)
 		return
 	}
 
 	// validate request
 	if err := h.validate.Struct(req); err != nil {
-		slog.Debug("failed to validate withdrawal request", slog.Any("error", err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		slog.DebugContext(r.Context(), "failed to validate withdrawal request", slog.Any("error", err))
+		h.badRequestResponse(w, contentType, err)
 		return
 	}
 
 	// process request
 	res, err := h.service.Withdrawal(r.Context(), req)
 	if err != nil {
-		slog.Debug("failed to process withdrawal", slog.Any("error", err))
-		h.errorResponse(w, contentType, http.StatusInternalServerError, err.Error())
+		slog.DebugContext(r.Context(), "failed to process withdrawal", slog.Any("error", err))
+		h.serviceErrorResponse(w, r, contentType, err)
 		return
 	}
 
 	// encode response
 	if err := paymenthttp.Encode(w, contentType, res); err != nil {
-		slog.Debug("failed to encode withdrawal response", slog.Any("error", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.DebugContext(r.Context(), "failed to encode withdrawal response", slog.Any("error", err))
+		h.internalErrorResponse(w, contentType)
 		return
 	}
 }
@@ -114,22 +137,23 @@ func (h *handler) callback(w http.ResponseWriter, r *http.Request) {
 	// decode request
 	var req model.TransactionStatusUpdate
 	if err := paymenthttp.Decode(r.Body, contentType, &req); err != nil {
-		slog.Debug("failed to decode transaction status update", slog.Any("error", err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		slog.DebugContext(r.Context(), "failed to decode transaction status update", slog.Any("error", err))
+		h.badRequestResponse(w, contentType, err)
 		return
 	}
 
 	// validate request
 	if err := h.validate.Struct(req); err != nil {
-		slog.Debug("failed to validate transaction status update", slog.Any("error", err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		slog.DebugContext(r.Context(), "failed to validate transaction status update", slog.Any("error", err))
+		h.badRequestResponse(w, contentType, err)
 		return
 	}
 
-	// process request
-	if err := h.service.UpdateStatus(r.Context(), req); err != nil {
-		slog.Debug("failed to update transaction status", slog.Any("error", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// process request, authenticated with the callback secret of the gateway, e.g. "Bearer <secret>"
+	ctx := withCallbackSecret(r.Context(), bearerToken(r.Header.Get(paymenthttp.HeaderAuthorization)))
+	if err := h.service.UpdateStatus(ctx, req); err != nil {
+		slog.DebugContext(r.Context(), "failed to update transaction status", slog.Any("error", err))
+		h.serviceErrorResponse(w, r, contentType, err)
 		return
 	}
 }
@@ -140,33 +164,287 @@ func (h *handler) getTransaction(w http.ResponseWriter, r *http.Request) {
 	// get transaction ID from path
 	id := r.PathValue("id")
 
+	// optionally wait for the transaction to reach a terminal status
+	if r.URL.Query().Has("waitFor") {
+		timeout, err := parseWaitForTerminal(r.URL.Query())
+		if err != nil {
+			slog.DebugContext(r.Context(), "failed to parse wait parameters", slog.Any("error", err))
+			h.errorResponse(w, contentType, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// the wait may outlast the write timeout of the server
+		extendDeadlines(http.NewResponseController(w), timeout+waitWriteMargin)
+
+		if err := h.waitForTerminal(r.Context(), id, timeout); err != nil {
+			slog.DebugContext(r.Context(), "failed to wait for transaction", slog.Any("error", err))
+			h.serviceErrorResponse(w, r, contentType, err)
+			return
+		}
+	}
+
 	// get transaction
 	tx, err := h.service.GetByID(r.Context(), id)
 	if err != nil {
-		slog.Debug("failed to get transaction", slog.Any("error", err))
-		h.errorResponse(w, contentType, http.StatusInternalServerError, err.Error())
+		slog.DebugContext(r.Context(), "failed to get transaction", slog.Any("error", err))
+		h.serviceErrorResponse(w, r, contentType, err)
 		return
 	}
 
+	// encode response, without the card number and CVV
+	if err := paymenthttp.Encode(w, contentType, tx.Masked()); err != nil {
+		slog.DebugContext(r.Context(), "failed to encode transaction", slog.Any("error", err))
+		h.internalErrorResponse(w, contentType)
+		return
+	}
+}
+
+func (h *handler) listGateways(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get(paymenthttp.HeaderContentType)
+
+	gateways := h.service.Gateways(r.Context())
+
 	// encode response
-	if err := paymenthttp.Encode(w, contentType, tx); err != nil {
-		slog.Debug("failed to encode transaction", slog.Any("error", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := paymenthttp.Encode(w, contentType, gateways); err != nil {
+		slog.DebugContext(r.Context(), "failed to encode gateways", slog.Any("error", err))
+		h.internalErrorResponse(w, contentType)
 		return
 	}
 }
 
+func (h *handler) dryRunRoute(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get(paymenthttp.HeaderContentType)
+
+	// decode request
+	var req model.RoutingDryRunRequest
+	if err := paymenthttp.Decode(r.Body, contentType, &req); err != nil {
+		slog.DebugContext(r.Context(), "failed to decode routing dry-run request", slog.Any("error", err))
+		h.badRequestResponse(w, contentType, err)
+		return
+	}
+
+	// validate request
+	if err := h.validate.Struct(req); err != nil {
+		slog.DebugContext(r.Context(), "failed to validate routing dry-run request", slog.Any("error", err))
+		h.badRequestResponse(w, contentType, err)
+		return
+	}
+
+	// explain routing
+	decision, err := h.service.DryRunRoute(r.Context(), req)
+	if err != nil {
+		slog.DebugContext(r.Context(), "failed to route transaction", slog.Any("error", err))
+		h.serviceErrorResponse(w, r, contentType, err)
+		return
+	}
+
+	// encode response
+	if err := paymenthttp.Encode(w, contentType, decision); err != nil {
+		slog.DebugContext(r.Context(), "failed to encode routing decision", slog.Any("error", err))
+		h.internalErrorResponse(w, contentType)
+		return
+	}
+}
+
+func (h *handler) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get(paymenthttp.HeaderContentType)
+
+	deliveries := h.webhooks.ListDeadLetters(r.Context())
+
+	// encode response
+	if err := paymenthttp.Encode(w, contentType, deliveries); err != nil {
+		slog.DebugContext(r.Context(), "failed to encode dead letters", slog.Any("error", err))
+		h.internalErrorResponse(w, contentType)
+		return
+	}
+}
+
+func (h *handler) replayWebhook(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get(paymenthttp.HeaderContentType)
+
+	// get delivery ID from path
+	id := r.PathValue("id")
+
+	// replay delivery
+	delivery, err := h.webhooks.Replay(r.Context(), id)
+	if err != nil {
+		slog.DebugContext(r.Context(), "failed to replay webhook", slog.Any("error", err))
+		h.serviceErrorResponse(w, r, contentType, err)
+		return
+	}
+
+	// encode response
+	if err := paymenthttp.Encode(w, contentType, delivery); err != nil {
+		slog.DebugContext(r.Context(), "failed to encode webhook delivery", slog.Any("error", err))
+		h.internalErrorResponse(w, contentType)
+		return
+	}
+}
+
+func (h *handler) getWeights(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get(paymenthttp.HeaderContentType)
+
+	weights := h.service.Weights(r.Context())
+
+	// encode response
+	if err := paymenthttp.Encode(w, contentType, weights); err != nil {
+		slog.DebugContext(r.Context(), "failed to encode gateway weights", slog.Any("error", err))
+		h.internalErrorResponse(w, contentType)
+		return
+	}
+}
+
+func (h *handler) setWeights(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get(paymenthttp.HeaderContentType)
+
+	// decode request
+	var req model.RoutingWeights
+	if err := paymenthttp.Decode(r.Body, contentType, &req); err != nil {
+		slog.DebugContext(r.Context(), "failed to decode gateway weights", slog.Any("error", err))
+		h.badRequestResponse(w, contentType, err)
+		return
+	}
+
+	// validate request
+	if err := h.validate.Struct(req); err != nil {
+		slog.DebugContext(r.Context(), "failed to validate gateway weights", slog.Any("error", err))
+		h.badRequestResponse(w, contentType, err)
+		return
+	}
+
+	// update weights
+	if err := h.service.SetWeights(r.Context(), req); err != nil {
+		slog.DebugContext(r.Context(), "failed to set gateway weights", slog.Any("error", err))
+		h.errorResponse(w, contentType, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// encode response
+	if err := paymenthttp.Encode(w, contentType, h.service.Weights(r.Context())); err != nil {
+		slog.DebugContext(r.Context(), "failed to encode gateway weights", slog.Any("error", err))
+		h.internalErrorResponse(w, contentType)
+		return
+	}
+}
+
+// liveness reports the process is running, regardless of its dependencies
+func (h *handler) liveness(w http.ResponseWriter, r *http.Request) {
+	h.healthResponse(w, r, model.HealthStatus{Status: model.HealthOK})
+}
+
+// readiness reports whether the service can take traffic, with the outcome of each check
+func (h *handler) readiness(w http.ResponseWriter, r *http.Request) {
+	status := h.health.Check(r.Context())
+	if status.Status != model.HealthOK {
+		slog.DebugContext(r.Context(), "service not ready", slog.Any("checks", status.Checks))
+	}
+
+	h.healthResponse(w, r, status)
+}
+
+// healthResponse writes the health status in JSON, with a 503 status code when failing
+func (h *handler) healthResponse(w http.ResponseWriter, r *http.Request, status model.HealthStatus) {
+	code := http.StatusOK
+	if status.Status != model.HealthOK {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+	w.Header().Set(paymenthttp.HeaderCacheControl, "no-store")
+	w.WriteHeader(code)
+
+	if err := paymenthttp.Encode(w, paymenthttp.MIMETypeJSON, status); err != nil {
+		slog.DebugContext(r.Context(), "failed to encode health status", slog.Any("error", err))
+	}
+}
+
+// errorStatusCode maps service errors to HTTP status codes
+func errorStatusCode(err error) int {
+	if errors.Is(err, ErrTransactionNotFound) || errors.Is(err, ErrDeliveryNotFound) {
+		return http.StatusNotFound
+	}
+
+	if errors.Is(err, ErrInvalidCallbackURL) {
+		return http.StatusBadRequest
+	}
+
+	if errors.Is(err, ErrCallbackUnauthenticated) {
+		return http.StatusUnauthorized
+	}
+
+	if errors.Is(err, ErrUnsupportedGateway) || errors.Is(err, ErrNotSupported) {
+		return http.StatusUnprocessableEntity
+	}
+
+	return http.StatusInternalServerError
+}
+
+// badRequestResponse writes a bad request error listing the invalid fields, if any
+func (h *handler) badRequestResponse(w http.ResponseWriter, contentType string, err error) {
+	// the body exceeds the limit set by LimitBody
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		h.errorResponse(w, contentType, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+		return
+	}
+
+	h.writeErrorResponse(w, contentType, model.ErrorResponse{
+		Code:    http.StatusBadRequest,
+		Message: http.StatusText(http.StatusBadRequest),
+		Errors:  toFieldErrors(paymenthttp.FieldErrors(err)),
+	})
+}
+
+func toFieldErrors(fieldErrs []paymenthttp.FieldError) []model.FieldError {
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+
+	out := make([]model.FieldError, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		out = append(out, model.FieldError{Field: fe.Field, Message: fe.Message})
+	}
+
+	return out
+}
+
 func (h *handler) errorResponse(w http.ResponseWriter, contentType string, code int, message string) {
-	er := model.ErrorResponse{
+	h.writeErrorResponse(w, contentType, model.ErrorResponse{
 		Code:    code,
 		Message: message,
+	})
+}
+
+// serviceErrorResponse writes the service error with its status code, not revealing the cause of internal errors
+func (h *handler) serviceErrorResponse(w http.ResponseWriter, r *http.Request, contentType string, err error) {
+	code := errorStatusCode(err)
+	if code == http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "server: request failed", slog.Any("error", err))
+		h.internalErrorResponse(w, contentType)
+		return
 	}
 
+	h.errorResponse(w, contentType, code, err.Error())
+}
+
+// internalErrorResponse writes an internal server error, its cause being logged only
+func (h *handler) internalErrorResponse(w http.ResponseWriter, contentType string) {
+	h.errorResponse(w, contentType, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+}
+
+func (h *handler) writeErrorResponse(w http.ResponseWriter, contentType string, er model.ErrorResponse) {
+	writeErrorResponse(w, contentType, er)
+}
+
+// writeErrorResponse writes the error with the request ID set on the response by the RequestID middleware, if any
+func writeErrorResponse(w http.ResponseWriter, contentType string, er model.ErrorResponse) {
+	er.RequestID = w.Header().Get(paymenthttp.HeaderRequestID)
+
 	b, err := paymenthttp.Marshal(contentType, er)
 	if err != nil {
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	http.Error(w, string(b), code)
+	http.Error(w, string(b), er.Code)
 }
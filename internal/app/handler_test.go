@@ -1,42 +1,69 @@
 package app
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 
 	paymenthttp "go-payment-service/pkg/http"
 	"go-payment-service/pkg/model"
+	"go-payment-service/pkg/webhook"
 	"go-payment-service/test/emulator"
 )
 
+// testCallbackSecret authenticates the callbacks of the emulator in the tests
+const testCallbackSecret = "callback-secret"
+
 type TestHandlerSuite struct {
 	suite.Suite
-	handler *handler
+	handler    *handler
+	router     *gatewayRouter
+	repository TransactionRepository
+	webhooks   *webhookService
 }
 
 // SetupSuite runs before all tests
 func (suite *TestHandlerSuite) SetupSuite() {
 	wg := &sync.WaitGroup{}
 
-	// Initialize HTTP client
-	resilientHTTPClient := paymenthttp.NewResilientHTTPClient()
-
 	// Initialize payment gateways
-	gatewayEmulator := emulator.Start()
+	gatewayEmulator := emulator.StartWithCallbackSecret(testCallbackSecret)
 
 	gateways := map[string]PaymentGateway{
-		"gatewayA": newGatewayAAdapter(resilientHTTPClient, gatewayEmulator.URL),
-		"gatewayB": newGatewayBAdapter(resilientHTTPClient, gatewayEmulator.URL),
+		"gatewayA": newGatewayAAdapter(paymenthttp.NewResilientHTTPClientWithConfig(paymenthttp.DefaultClientConfig("gatewayA")), gatewayEmulator.URL),
+		"gatewayB": newGatewayBAdapter(paymenthttp.NewResilientHTTPClientWithConfig(paymenthttp.DefaultClientConfig("gatewayB")), gatewayEmulator.URL),
 	}
 
-	repository := newMemoryTransactionRepository()
-	service := newTransactionService(wg, gateways, repository)
-	suite.handler = newHandler(service)
+	suite.webhooks = newWebhookService(newMemoryWebhookRepository(), []byte("secret"))
+	suite.webhooks.maxAttempts = 2
+	suite.webhooks.baseDelay = 10 * time.Millisecond
+	suite.webhooks.allowPrivateURLs = true // the merchants are httptest servers
+	suite.webhooks.Start()
+
+	suite.repository = newMemoryTransactionRepository()
+	suite.router = newGatewayRouter(gateways, nil, "gatewayA", "gatewayB")
+	suite.router.setCallbackSecrets(map[string]string{"gatewayA": testCallbackSecret, "gatewayB": testCallbackSecret})
+	service := newTransactionService(wg, suite.router, suite.repository, newTransactionBroker(), suite.webhooks)
+	suite.handler = newHandler(service, suite.webhooks, newHealthChecker())
+}
+
+// TearDownSuite runs after all tests
+func (suite *TestHandlerSuite) TearDownSuite() {
+	_ = suite.webhooks.Stop(context.Background())
 }
 
 func (suite *TestHandlerSuite) TestDeposit() {
@@ -101,6 +128,53 @@ func (suite *TestHandlerSuite) TestDeposit() {
 			},
 			expectedCode: http.StatusOK,
 		},
+		{
+			name: "currency not supported by gateway",
+			given: model.DepositRequest{
+				BaseRequest: model.BaseRequest{
+					Amount: model.Money{
+						Amount:   1000,
+						Currency: "GBP",
+					},
+					CardDetails: model.CardDetails{
+						Number:      "4111111111111111",
+						Name:        "John Doe",
+						ExpiryMonth: 12,
+						ExpiryYear:  2023,
+						CVV:         "123",
+					},
+					GatewayDetails: model.GatewayDetails{
+						ID: "gatewayB",
+					},
+				},
+			},
+			givenMIMEType: paymenthttp.MIMETypeJSON,
+			expectedCode:  http.StatusUnprocessableEntity,
+		},
+		{
+			name: "invalid callback URL",
+			given: model.DepositRequest{
+				BaseRequest: model.BaseRequest{
+					Amount: model.Money{
+						Amount:   1000,
+						Currency: "USD",
+					},
+					CardDetails: model.CardDetails{
+						Number:      "4111111111111111",
+						Name:        "John Doe",
+						ExpiryMonth: 12,
+						ExpiryYear:  2023,
+						CVV:         "123",
+					},
+					GatewayDetails: model.GatewayDetails{
+						ID:          "gatewayA",
+						CallbackURL: "file:///etc/passwd",
+					},
+				},
+			},
+			givenMIMEType: paymenthttp.MIMETypeJSON,
+			expectedCode:  http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -116,6 +190,9 @@ func (suite *TestHandlerSuite) TestDeposit() {
 			suite.handler.mux.ServeHTTP(w, r)
 
 			suite.Equal(tc.expectedCode, w.Code)
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
 
 			var resp model.DepositResponse
 			err = paymenthttp.Decode(w.Body, tc.givenMIMEType, &resp)
@@ -219,6 +296,714 @@ func (suite *TestHandlerSuite) TestWithdrawal() {
 	}
 }
 
+func (suite *TestHandlerSuite) TestDepositForm() {
+	testCases := []struct {
+		name           string
+		given          url.Values
+		expected       model.Transaction
+		expectedCode   int
+		expectedErrors []model.FieldError
+	}{
+		{
+			name: "pending",
+			given: url.Values{
+				"amount.amount":           {"1000"},
+				"amount.currency":         {"USD"},
+				"cardDetails.number":      {"4111111111111111"},
+				"cardDetails.name":        {"John Doe"},
+				"cardDetails.expiryMonth": {"12"},
+				"cardDetails.expiryYear":  {"2023"},
+				"cardDetails.cvv":         {"123"},
+				"gatewayDetails.id":       {"gatewayA"},
+			},
+			expected: model.Transaction{
+				Status: model.Pending,
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "invalid fields",
+			given: url.Values{
+				"amount.amount":           {"1000"},
+				"amount.currency":         {"USD"},
+				"cardDetails.number":      {"4111111111111111"},
+				"cardDetails.expiryMonth": {"13"},
+				"cardDetails.expiryYear":  {"2023"},
+				"cardDetails.cvv":         {"123"},
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedErrors: []model.FieldError{
+				{Field: "cardDetails.name", Message: "is required"},
+				{Field: "cardDetails.expiryMonth", Message: "must be at most 12"},
+			},
+		},
+		{
+			name: "malformed field",
+			given: url.Values{
+				"amount.amount": {"one thousand"},
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedErrors: []model.FieldError{
+				{Field: "amount.amount", Message: `invalid number "one thousand"`},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			r := httptest.NewRequest(http.MethodPost, "/deposit", strings.NewReader(tc.given.Encode()))
+			r.Header.Add(paymenthttp.HeaderContentType, paymenthttp.MIMETypeForm)
+
+			suite.handler.mux.ServeHTTP(w, r)
+
+			suite.Equal(tc.expectedCode, w.Code)
+
+			if tc.expectedErrors != nil {
+				var resp model.ErrorResponse
+				err := paymenthttp.Decode(w.Body, paymenthttp.MIMETypeJSON, &resp)
+				suite.Require().NoError(err)
+
+				suite.Equal(tc.expectedErrors, resp.Errors)
+				return
+			}
+
+			var resp model.DepositResponse
+			err := paymenthttp.Decode(w.Body, paymenthttp.MIMETypeJSON, &resp)
+			suite.Require().NoError(err)
+
+			suite.NotEmpty(resp.TransactionID)
+			suite.Equal(tc.expected.Status, resp.Status)
+		})
+	}
+}
+
+func (suite *TestHandlerSuite) TestExport() {
+	// create transactions to export
+	for _, gatewayID := range []string{"gatewayA", "gatewayB"} {
+		b, err := paymenthttp.Marshal(paymenthttp.MIMETypeJSON, model.DepositRequest{
+			BaseRequest: model.BaseRequest{
+				Amount: model.Money{Amount: 10, Currency: "EUR"},
+				CardDetails: model.CardDetails{
+					Number:      "4111111111111111",
+					Name:        "John Doe",
+					ExpiryMonth: 12,
+					ExpiryYear:  2030,
+					CVV:         "123",
+				},
+				GatewayDetails: model.GatewayDetails{ID: gatewayID},
+			},
+		})
+		suite.Require().NoError(err)
+
+		r := httptest.NewRequest(http.MethodPost, "/deposit", bytes.NewReader(b))
+		r.Header.Add(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+		suite.handler.mux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	testCases := []struct {
+		name         string
+		givenQuery   string
+		givenAccept  string
+		expectedCode int
+		expectedType string
+	}{
+		{
+			name:         "csv",
+			givenQuery:   "?gateway=gatewayB&from=2020-01-01",
+			givenAccept:  paymenthttp.MIMETypeCSV,
+			expectedCode: http.StatusOK,
+			expectedType: paymenthttp.MIMETypeCSV,
+		},
+		{
+			name:         "ndjson",
+			givenQuery:   "?gateway=gatewayB&status=succeeded",
+			givenAccept:  paymenthttp.MIMETypeNDJSON,
+			expectedCode: http.StatusOK,
+			expectedType: paymenthttp.MIMETypeNDJSON,
+		},
+		{
+			name:         "not acceptable",
+			givenAccept:  paymenthttp.MIMETypeXML,
+			expectedCode: http.StatusNotAcceptable,
+		},
+		{
+			name:         "invalid date",
+			givenQuery:   "?from=yesterday",
+			givenAccept:  paymenthttp.MIMETypeCSV,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown status",
+			givenQuery:   "?status=sucess",
+			givenAccept:  paymenthttp.MIMETypeCSV,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			r := httptest.NewRequest(http.MethodGet, "/transactions/export"+tc.givenQuery, nil)
+			r.Header.Add(paymenthttp.HeaderAccept, tc.givenAccept)
+
+			suite.handler.mux.ServeHTTP(w, r)
+
+			suite.Equal(tc.expectedCode, w.Code)
+
+			switch tc.expectedType {
+			case paymenthttp.MIMETypeCSV:
+				records, err := csv.NewReader(w.Body).ReadAll()
+				suite.Require().NoError(err)
+				suite.Require().Greater(len(records), 1)
+
+				suite.Equal(exportCSVHeader, records[0])
+				for _, record := range records[1:] {
+					suite.Equal("411111******1111", record[7])
+					suite.Equal("gatewayB", record[9])
+				}
+			case paymenthttp.MIMETypeNDJSON:
+				scanner := bufio.NewScanner(w.Body)
+
+				lines := 0
+				for scanner.Scan() {
+					var tx model.Transaction
+					suite.Require().NoError(json.Unmarshal(scanner.Bytes(), &tx))
+
+					suite.Equal("411111******1111", tx.CardDetails.Number)
+					suite.Empty(tx.CardDetails.CVV)
+					suite.Equal(model.Succeeded, tx.Status)
+					lines++
+				}
+
+				suite.Positive(lines)
+			}
+		})
+	}
+}
+
+func (suite *TestHandlerSuite) TestStreamTransaction() {
+	tx := model.Transaction{
+		ID:             "stream-tx",
+		ExternalID:     "stream-external-tx",
+		Type:           model.Deposit,
+		Status:         model.Pending,
+		CardDetails:    model.CardDetails{Number: "4111111111111111", CVV: "123"},
+		GatewayDetails: model.GatewayDetails{ID: "gatewayA"},
+	}
+	suite.Require().NoError(suite.repository.Create(&tx))
+
+	server := httptest.NewServer(suite.handler.mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/transactions/stream-tx/stream")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal(paymenthttp.MIMETypeEventStream, resp.Header.Get(paymenthttp.HeaderContentType))
+
+	events := make(chan model.Transaction)
+	go func() {
+		defer close(events)
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, found := strings.CutPrefix(scanner.Text(), "data: ")
+			if !found {
+				continue
+			}
+
+			var event model.Transaction
+			if err := json.Unmarshal([]byte(data), &event); err == nil {
+				events <- event
+			}
+		}
+	}()
+
+	// current state
+	event := <-events
+	suite.Equal(model.Pending, event.Status)
+	suite.Equal("411111******1111", event.CardDetails.Number)
+
+	// gateway callback
+	b, err := json.Marshal(model.TransactionStatusUpdate{TransactionID: tx.ExternalID, Status: model.Succeeded})
+	suite.Require().NoError(err)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/callback", bytes.NewReader(b))
+	suite.Require().NoError(err)
+	req.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+	req.Header.Set(paymenthttp.HeaderAuthorization, "Bearer "+testCallbackSecret)
+
+	callback, err := http.DefaultClient.Do(req)
+	suite.Require().NoError(err)
+	callback.Body.Close()
+
+	event = <-events
+	suite.Equal(model.Succeeded, event.Status)
+
+	// the stream is closed after the terminal status
+	_, open := <-events
+	suite.False(open)
+}
+
+func (suite *TestHandlerSuite) TestStreamTransactionNotFound() {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/transactions/unknown/stream", nil)
+
+	suite.handler.mux.ServeHTTP(w, r)
+
+	suite.Equal(http.StatusNotFound, w.Code)
+}
+
+func (suite *TestHandlerSuite) TestCallbackAuthentication() {
+	tx := model.Transaction{ID: "callback-tx", ExternalID: "callback-external-tx", Status: model.Pending, GatewayDetails: model.GatewayDetails{ID: "gatewayA"}}
+	suite.Require().NoError(suite.repository.Create(&tx))
+
+	testCases := []struct {
+		name           string
+		givenSecrets   map[string]string
+		givenSecret    string
+		expectedCode   int
+		expectedStatus model.TransactionStatus
+	}{
+		{
+			name:           "missing secret",
+			expectedCode:   http.StatusUnauthorized,
+			expectedStatus: model.Pending,
+		},
+		{
+			name:           "secret of another gateway",
+			givenSecrets:   map[string]string{"gatewayA": "secret-a", "gatewayB": "secret-b"},
+			givenSecret:    "secret-b",
+			expectedCode:   http.StatusUnauthorized,
+			expectedStatus: model.Pending,
+		},
+		{
+			name:           "gateway without secret",
+			givenSecrets:   map[string]string{"gatewayA": "", "gatewayB": "secret-b"},
+			expectedCode:   http.StatusUnauthorized,
+			expectedStatus: model.Pending,
+		},
+		{
+			name:           "secret of the gateway",
+			givenSecret:    testCallbackSecret,
+			expectedCode:   http.StatusOK,
+			expectedStatus: model.Succeeded,
+		},
+	}
+
+	defer suite.router.setCallbackSecrets(suite.router.table.Load().callbackSecrets)
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			if tc.givenSecrets != nil {
+				suite.router.setCallbackSecrets(tc.givenSecrets)
+			} else {
+				suite.router.setCallbackSecrets(map[string]string{"gatewayA": testCallbackSecret, "gatewayB": testCallbackSecret})
+			}
+
+			b, err := json.Marshal(model.TransactionStatusUpdate{TransactionID: tx.ExternalID, Status: model.Succeeded})
+			suite.Require().NoError(err)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/callback", bytes.NewReader(b))
+			r.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+			if tc.givenSecret != "" {
+				r.Header.Set(paymenthttp.HeaderAuthorization, "Bearer "+tc.givenSecret)
+			}
+
+			suite.handler.mux.ServeHTTP(w, r)
+
+			suite.Equal(tc.expectedCode, w.Code)
+
+			current, err := suite.repository.GetByID(tx.ID)
+			suite.Require().NoError(err)
+			suite.Equal(tc.expectedStatus, current.Status)
+		})
+	}
+}
+
+func (suite *TestHandlerSuite) TestServiceErrorResponse() {
+	testCases := []struct {
+		name            string
+		givenErr        error
+		expectedCode    int
+		expectedMessage string
+	}{
+		{
+			name:            "not found",
+			givenErr:        fmt.Errorf("could not find transaction. err: %w", ErrTransactionNotFound),
+			expectedCode:    http.StatusNotFound,
+			expectedMessage: "could not find transaction. err: transaction not found",
+		},
+		{
+			name:            "internal error",
+			givenErr:        errors.New("repository: connection refused to 10.0.0.1"),
+			expectedCode:    http.StatusInternalServerError,
+			expectedMessage: http.StatusText(http.StatusInternalServerError),
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			w.Header().Set(paymenthttp.HeaderRequestID, "req-1")
+			r := httptest.NewRequest(http.MethodGet, "/transactions/tx-1", nil)
+
+			suite.handler.serviceErrorResponse(w, r, paymenthttp.MIMETypeJSON, tc.givenErr)
+
+			suite.Equal(tc.expectedCode, w.Code)
+
+			var er model.ErrorResponse
+			suite.Require().NoError(json.NewDecoder(w.Body).Decode(&er))
+			suite.Equal(tc.expectedMessage, er.Message)
+			suite.Equal("req-1", er.RequestID)
+		})
+	}
+}
+
+func (suite *TestHandlerSuite) TestGetTransactionWaitForTerminal() {
+	for _, id := range []string{"wait-tx", "wait-timeout-tx"} {
+		tx := model.Transaction{
+			ID:             id,
+			ExternalID:     id + "-external",
+			Type:           model.Deposit,
+			Status:         model.Pending,
+			CardDetails:    model.CardDetails{Number: "4111111111111111", CVV: "987"},
+			GatewayDetails: model.GatewayDetails{ID: "gatewayA"},
+		}
+		suite.Require().NoError(suite.repository.Create(&tx))
+	}
+
+	testCases := []struct {
+		name          string
+		givenPath     string
+		givenCallback bool
+		expected      model.TransactionStatus
+		expectedCode  int
+	}{
+		{
+			name:          "terminal",
+			givenPath:     "/transactions/wait-tx?waitFor=terminal&timeout=5s",
+			givenCallback: true,
+			expected:      model.Succeeded,
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:         "timeout",
+			givenPath:    "/transactions/wait-timeout-tx?waitFor=terminal&timeout=50ms",
+			expected:     model.Pending,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not found",
+			givenPath:    "/transactions/unknown?waitFor=terminal&timeout=50ms",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid timeout",
+			givenPath:    "/transactions/wait-tx?waitFor=terminal&timeout=soon",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid wait",
+			givenPath:    "/transactions/wait-tx?waitFor=succeeded",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			if tc.givenCallback {
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+
+					b, _ := json.Marshal(model.TransactionStatusUpdate{TransactionID: "wait-tx-external", Status: model.Succeeded})
+					r := httptest.NewRequest(http.MethodPost, "/callback", bytes.NewReader(b))
+					r.Header.Set(paymenthttp.HeaderAuthorization, "Bearer "+testCallbackSecret)
+					suite.handler.mux.ServeHTTP(httptest.NewRecorder(), r)
+				}()
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.givenPath, nil)
+
+			suite.handler.mux.ServeHTTP(w, r)
+
+			suite.Equal(tc.expectedCode, w.Code)
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			suite.NotContains(w.Body.String(), "987", "the CVV is never returned")
+			suite.NotContains(w.Body.String(), "4111111111111111")
+
+			var tx model.Transaction
+			suite.Require().NoError(json.NewDecoder(w.Body).Decode(&tx))
+			suite.Equal(tc.expected, tx.Status)
+			suite.Equal("411111******1111", tx.CardDetails.Number)
+		})
+	}
+}
+
+func (suite *TestHandlerSuite) TestGetTransactionWaitOutlastingWriteTimeout() {
+	tx := model.Transaction{ID: "wait-long-tx", Type: model.Deposit, Status: model.Pending}
+	suite.Require().NoError(suite.repository.Create(&tx))
+
+	ts := httptest.NewUnstartedServer(suite.handler.mux)
+	ts.Config.WriteTimeout = 50 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/transactions/wait-long-tx?waitFor=terminal&timeout=200ms")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	var got model.Transaction
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&got))
+	suite.Equal(model.Pending, got.Status)
+}
+
+func (suite *TestHandlerSuite) TestWebhooks() {
+	var mu sync.Mutex
+	var received []model.WebhookEvent
+	failing := true
+
+	merchant := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify([]byte("secret"), r.Header.Get(webhook.HeaderSignature), body, time.Minute); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var event model.WebhookEvent
+		_ = json.Unmarshal(body, &event)
+		received = append(received, event)
+	}))
+	defer merchant.Close()
+
+	b, err := paymenthttp.Marshal(paymenthttp.MIMETypeJSON, model.DepositRequest{
+		BaseRequest: model.BaseRequest{
+			Amount: model.Money{Amount: 10, Currency: "EUR"},
+			CardDetails: model.CardDetails{
+				Number:      "4111111111111111",
+				Name:        "John Doe",
+				ExpiryMonth: 12,
+				ExpiryYear:  2030,
+				CVV:         "123",
+			},
+			GatewayDetails: model.GatewayDetails{ID: "gatewayA", CallbackURL: merchant.URL},
+		},
+	})
+	suite.Require().NoError(err)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/deposit", bytes.NewReader(b))
+	r.Header.Add(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+	suite.handler.mux.ServeHTTP(w, r)
+
+	var resp model.DepositResponse
+	suite.Require().NoError(json.NewDecoder(w.Body).Decode(&resp))
+
+	// the delivery is dead-lettered once its attempts are exhausted
+	var deadLetters []model.WebhookDelivery
+	suite.Eventually(func() bool {
+		w := httptest.NewRecorder()
+		suite.handler.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/webhooks/dead-letters", nil))
+
+		deadLetters = nil
+		_ = json.NewDecoder(w.Body).Decode(&deadLetters)
+
+		return len(deadLetters) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	suite.Equal(resp.TransactionID, deadLetters[0].Event.TransactionID)
+	suite.Equal(model.Succeeded, deadLetters[0].Event.Status)
+	suite.Equal(2, deadLetters[0].Attempts)
+
+	// replaying delivers it once the merchant is back
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+
+	w = httptest.NewRecorder()
+	suite.handler.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/webhooks/"+deadLetters[0].ID+"/replay", nil))
+	suite.Equal(http.StatusOK, w.Code)
+
+	suite.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(received) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	suite.Equal(resp.TransactionID, received[0].TransactionID)
+
+	// unknown deliveries can't be replayed
+	w = httptest.NewRecorder()
+	suite.handler.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/webhooks/unknown/replay", nil))
+	suite.Equal(http.StatusNotFound, w.Code)
+}
+
+func (suite *TestHandlerSuite) TestListGateways() {
+	r := httptest.NewRequest(http.MethodGet, "/gateways", nil)
+	r.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+	w := httptest.NewRecorder()
+
+	suite.handler.mux.ServeHTTP(w, r)
+
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	var gateways []model.GatewayStatus
+	suite.Require().NoError(json.NewDecoder(w.Body).Decode(&gateways))
+	suite.Require().Len(gateways, 2)
+	suite.Equal("gatewayA", gateways[0].ID)
+	suite.Equal("gatewayB", gateways[1].ID)
+
+	for _, gateway := range gateways {
+		suite.Equal("closed", gateway.CircuitBreaker.State)
+		suite.NotEmpty(gateway.Capabilities.Currencies)
+	}
+}
+
+func (suite *TestHandlerSuite) TestDryRunRoute() {
+	// restore the routing without rules once done, as the router is shared between tests
+	table := suite.router.table.Load()
+	defer suite.router.Reload(table.gateways, table.rules, table.priority...)
+
+	suite.router.Reload(table.gateways, &routingRules{Rules: []routingRule{
+		{Name: "large-eur-to-b", Gateway: "gatewayB", Currencies: []string{"EUR"}, MinAmount: 1000},
+	}}, table.priority...)
+
+	testCases := []struct {
+		name         string
+		given        string
+		expected     []string
+		expectedRule string
+		expectedCode int
+	}{
+		{
+			name:         "by priority",
+			given:        `{"type":"deposit","amount":{"amount":10,"currency":"EUR"},"cardNumber":"4111111111111111"}`,
+			expected:     []string{"gatewayA", "gatewayB"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "matched rule",
+			given:        `{"type":"deposit","amount":{"amount":1500,"currency":"EUR"},"cardNumber":"4111111111111111"}`,
+			expected:     []string{"gatewayB", "gatewayA"},
+			expectedRule: "large-eur-to-b",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "capabilities",
+			given:        `{"type":"deposit","amount":{"amount":10,"currency":"EUR"},"cardNumber":"378282246310005"}`,
+			expected:     []string{"gatewayA"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "unsupported gateway",
+			given:        `{"type":"deposit","amount":{"amount":10,"currency":"EUR"},"cardNumber":"378282246310005","gatewayDetails":{"id":"gatewayZ"}}`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "unsupported currency",
+			given:        `{"type":"deposit","amount":{"amount":10,"currency":"JPY"},"cardNumber":"4111111111111111"}`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "invalid type",
+			given:        `{"type":"refund","amount":{"amount":10,"currency":"EUR"},"cardNumber":"378282246310005"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/routing/dry-run", strings.NewReader(tc.given))
+			r.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+			w := httptest.NewRecorder()
+
+			suite.handler.mux.ServeHTTP(w, r)
+
+			suite.Equal(tc.expectedCode, w.Code)
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			var decision model.RoutingDecision
+			suite.Require().NoError(json.NewDecoder(w.Body).Decode(&decision))
+			suite.Equal(tc.expected, decision.Gateways)
+			suite.Equal(tc.expectedRule, decision.Rule)
+			suite.Len(decision.Excluded, 2-len(tc.expected))
+		})
+	}
+}
+
+func (suite *TestHandlerSuite) TestWeights() {
+	// disable the weighted split once done, as the handler is shared between tests
+	defer func() {
+		suite.Require().NoError(suite.handler.service.SetWeights(context.Background(), model.RoutingWeights{}))
+	}()
+
+	testCases := []struct {
+		name         string
+		given        string
+		expectedCode int
+	}{
+		{
+			name:         "valid weights",
+			given:        `{"weights":[{"gatewayId":"gatewayA","weight":90},{"gatewayId":"gatewayB","weight":10}]}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not adding up to 100",
+			given:        `{"weights":[{"gatewayId":"gatewayA","weight":90},{"gatewayId":"gatewayB","weight":30}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "out of range weight",
+			given:        `{"weights":[{"gatewayId":"gatewayA","weight":110}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/admin/routing/weights", strings.NewReader(tc.given))
+			r.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+			w := httptest.NewRecorder()
+
+			suite.handler.mux.ServeHTTP(w, r)
+
+			suite.Equal(tc.expectedCode, w.Code)
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/routing/weights", nil)
+	r.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+	w := httptest.NewRecorder()
+
+	suite.handler.mux.ServeHTTP(w, r)
+
+	var weights model.RoutingWeights
+	suite.Require().NoError(json.NewDecoder(w.Body).Decode(&weights))
+	suite.Equal([]model.GatewayWeight{{GatewayID: "gatewayA", Weight: 90}, {GatewayID: "gatewayB", Weight: 10}}, weights.Weights)
+}
+
 func TestTestHandlerSuite(t *testing.T) {
 	suite.Run(t, new(TestHandlerSuite))
 }
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sony/gobreaker"
+
+	"go-payment-service/pkg/model"
+)
+
+// defaultHealthCheckTimeout bounds each health check, so a hanging dependency reports a failure instead of
+// making the probe time out
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// HealthCheck reports whether a dependency of the service is available, it must return once ctx is done
+type HealthCheck func(ctx context.Context) error
+
+type namedHealthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// healthChecker runs the readiness checks of the service
+type healthChecker struct {
+	checks  []namedHealthCheck
+	timeout time.Duration
+}
+
+func newHealthChecker() *healthChecker {
+	return &healthChecker{timeout: defaultHealthCheckTimeout}
+}
+
+// Register adds a readiness check, checks must be registered before the server starts
+func (c *healthChecker) Register(name string, check HealthCheck) {
+	c.checks = append(c.checks, namedHealthCheck{name: name, check: check})
+}
+
+// Check runs the checks concurrently and reports their outcome in registration order,
+// the service being ready only when all of them pass
+func (c *healthChecker) Check(ctx context.Context) model.HealthStatus {
+	status := model.HealthStatus{Status: model.HealthOK, Checks: make([]model.HealthCheck, len(c.checks))}
+
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			status.Checks[i] = c.run(ctx, nc)
+		}()
+	}
+	wg.Wait()
+
+	for _, check := range status.Checks {
+		if check.Status != model.HealthOK {
+			status.Status = model.HealthFail
+		}
+	}
+
+	return status
+}
+
+func (c *healthChecker) run(ctx context.Context, nc namedHealthCheck) model.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	result := model.HealthCheck{Name: nc.name, Status: model.HealthOK}
+	if err := nc.check(ctx); err != nil {
+		result.Status, result.Error = model.HealthFail, err.Error()
+	}
+	result.Duration = time.Since(start).String()
+
+	return result
+}
+
+// repositoryHealthCheck checks the transaction repository is reachable
+func repositoryHealthCheck(repository TransactionRepository) HealthCheck {
+	return func(ctx context.Context) error {
+		return repository.Ping(ctx)
+	}
+}
+
+// gatewaysHealthCheck checks at least one registered gateway has its circuit breaker closed,
+// the gateways without circuit breaker being considered available
+func gatewaysHealthCheck(router *gatewayRouter) HealthCheck {
+	return func(ctx context.Context) error {
+		ids := router.ordered()
+
+		for _, id := range ids {
+			gateway, exists := router.Gateway(id)
+			if !exists {
+				continue
+			}
+
+			r, ok := gateway.(breakerReporter)
+			if !ok || r.BreakerState().State == gobreaker.StateClosed.String() {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("no gateway with a closed circuit breaker among %v", ids)
+	}
+}
+
+// drainingHealthCheck fails once the server is shutting down
+func drainingHealthCheck(draining *atomic.Bool) HealthCheck {
+	return func(ctx context.Context) error {
+		if draining.Load() {
+			return errors.New("shutting down")
+		}
+
+		return nil
+	}
+}
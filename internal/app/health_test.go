@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/suite"
+
+	"go-payment-service/pkg/model"
+)
+
+type TestHealthSuite struct {
+	suite.Suite
+}
+
+func (suite *TestHealthSuite) TestReadiness() {
+	hanging := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	testCases := []struct {
+		name         string
+		given        map[string]HealthCheck
+		expected     model.HealthState
+		expectedCode int
+		expectedErr  string
+	}{
+		{
+			name: "ready",
+			given: map[string]HealthCheck{
+				"repository": repositoryHealthCheck(newMemoryTransactionRepository()),
+			},
+			expected:     model.HealthOK,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "failing check",
+			given: map[string]HealthCheck{
+				"failing": func(ctx context.Context) error { return errors.New("unreachable") },
+			},
+			expected:     model.HealthFail,
+			expectedCode: http.StatusServiceUnavailable,
+			expectedErr:  "unreachable",
+		},
+		{
+			name:         "hanging check",
+			given:        map[string]HealthCheck{"hanging": hanging},
+			expected:     model.HealthFail,
+			expectedCode: http.StatusServiceUnavailable,
+			expectedErr:  context.DeadlineExceeded.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			health := newHealthChecker()
+			health.timeout = 10 * time.Millisecond
+			for name, check := range tc.given {
+				health.Register(name, check)
+			}
+
+			h := newHandler(nil, nil, health)
+
+			w := httptest.NewRecorder()
+			h.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			suite.Equal(tc.expectedCode, w.Code)
+
+			var status model.HealthStatus
+			suite.Require().NoError(json.NewDecoder(w.Body).Decode(&status))
+			suite.Equal(tc.expected, status.Status)
+			suite.Require().Len(status.Checks, 1)
+			suite.Equal(tc.expectedErr, status.Checks[0].Error)
+
+			// the process is alive regardless of its dependencies
+			w = httptest.NewRecorder()
+			h.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			suite.Equal(http.StatusOK, w.Code)
+		})
+	}
+}
+
+func (suite *TestHealthSuite) TestGatewaysHealthCheck() {
+	gatewayA := &stubGateway{breaker: gobreaker.StateOpen}
+	gatewayB := &stubGateway{breaker: gobreaker.StateHalfOpen}
+	check := gatewaysHealthCheck(newGatewayRouter(map[string]PaymentGateway{"gatewayA": gatewayA, "gatewayB": gatewayB}, nil))
+
+	suite.EqualError(check(context.Background()), "no gateway with a closed circuit breaker among [gatewayA gatewayB]")
+
+	gatewayB.breaker = gobreaker.StateClosed
+	suite.NoError(check(context.Background()))
+}
+
+func (suite *TestHealthSuite) TestDrainingHealthCheck() {
+	var draining atomic.Bool
+	check := drainingHealthCheck(&draining)
+
+	suite.NoError(check(context.Background()))
+
+	draining.Store(true)
+	suite.EqualError(check(context.Background()), "shutting down")
+}
+
+func TestTestHealthSuite(t *testing.T) {
+	suite.Run(t, new(TestHealthSuite))
+}
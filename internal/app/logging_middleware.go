@@ -5,6 +5,12 @@ import (
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
 	paymenthttp "go-payment-service/pkg/http"
 )
 
@@ -18,17 +24,46 @@ func (w *wrappedWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Unwrap returns the original ResponseWriter, allowing http.ResponseController to reach it
+func (w *wrappedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Logging logs every request once served, in the server span of its trace. The trace of the caller
+// (e.g. a gateway sending a callback) is continued when the request carries a W3C trace context.
+// The trace ID is logged by the handler returned by NewContextLogHandler.
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		r = r.WithContext(ctx)
 		wrapped := &wrappedWriter{w, http.StatusOK}
 
 		// Call the next handler
 		next.ServeHTTP(wrapped, r)
 
+		// the mux sets the pattern the request matched, e.g. "GET /transactions/{id}"
+		if r.Pattern != "" {
+			span.SetName(r.Pattern)
+			span.SetAttributes(attribute.String("http.route", r.Pattern))
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", wrapped.statusCode))
+		if wrapped.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrapped.statusCode))
+		}
+
 		slog.InfoContext(
-			r.Context(),
+			ctx,
 			"operation completed",
 			slog.Any("status", wrapped.statusCode),
 			slog.String("method", r.Method),
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"go-payment-service/internal/config"
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+	paymentv1 "go-payment-service/pkg/pb/payment/v1"
+)
+
+var (
+	// ErrUnauthenticated is returned when a request has no valid merchant API key
+	ErrUnauthenticated = errors.New("missing or invalid API key")
+	// ErrForbidden is returned when the merchant is not allowed to call an admin route
+	ErrForbidden = errors.New("admin role required")
+)
+
+// merchantAuthenticator resolves the merchants from their API keys, of which it only knows the SHA-256 hashes
+type merchantAuthenticator struct {
+	merchants map[string]model.Merchant // by API key hash
+}
+
+// newMerchantAuthenticator creates the authenticator of the configured merchants, rejecting every request
+// when there is none
+func newMerchantAuthenticator(merchants []config.Merchant) *merchantAuthenticator {
+	a := &merchantAuthenticator{merchants: make(map[string]model.Merchant, len(merchants))}
+	for _, m := range merchants {
+		a.merchants[strings.ToLower(m.APIKeyHash)] = model.Merchant{ID: m.ID, Name: m.Name, Admin: m.Admin}
+	}
+
+	return a
+}
+
+// Authenticate returns the merchant the API key belongs to
+func (a *merchantAuthenticator) Authenticate(apiKey string) (model.Merchant, error) {
+	if apiKey == "" {
+		return model.Merchant{}, ErrUnauthenticated
+	}
+
+	sum := sha256.Sum256([]byte(apiKey))
+
+	merchant, exists := a.merchants[hex.EncodeToString(sum[:])]
+	if !exists {
+		return model.Merchant{}, ErrUnauthenticated
+	}
+
+	return merchant, nil
+}
+
+// bearerToken returns the token of an Authorization header value using the Bearer scheme
+func bearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+type merchantKey struct{}
+
+// withMerchant returns a copy of ctx carrying the authenticated merchant
+func withMerchant(ctx context.Context, merchant model.Merchant) context.Context {
+	return context.WithValue(ctx, merchantKey{}, merchant)
+}
+
+// merchantFromContext returns the authenticated merchant of the request, the zero merchant when
+// the API is not authenticated
+func merchantFromContext(ctx context.Context) model.Merchant {
+	merchant, _ := ctx.Value(merchantKey{}).(model.Merchant)
+	return merchant
+}
+
+// ownedBy reports whether the transaction belongs to the merchant of the request
+func ownedBy(ctx context.Context, tx *model.Transaction) bool {
+	return tx.MerchantID == merchantFromContext(ctx).ID
+}
+
+// authenticate resolves the merchant of the requests from the API key of their Authorization header, e.g.
+// "Bearer <API key>", into their context, and rejects them with 401 Unauthorized when it is missing or
+// invalid. The public routes, matched on the mux, are served without authentication, and the admin routes
+// (under /admin/) to the merchants with the admin role only, the others get 403 Forbidden.
+func authenticate(mux *http.ServeMux, merchants *merchantAuthenticator, public ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		if merchants == nil {
+			return next
+		}
+
+		publicRoutes := make(map[string]bool, len(public))
+		for _, route := range public {
+			publicRoutes[route] = true
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// unmatched requests are answered by the mux, with 404 or 405
+			_, pattern := mux.Handler(r)
+			if pattern == "" || publicRoutes[pattern] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			merchant, err := merchants.Authenticate(bearerToken(r.Header.Get(paymenthttp.HeaderAuthorization)))
+			if err != nil {
+				slog.DebugContext(r.Context(), "failed to authenticate merchant", slog.Any("error", err))
+				w.Header().Set(paymenthttp.HeaderWWWAuthenticate, "Bearer")
+				writeErrorResponse(w, r.Header.Get(paymenthttp.HeaderContentType), model.ErrorResponse{
+					Code:    http.StatusUnauthorized,
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if adminRoute(pattern) && !merchant.Admin {
+				slog.DebugContext(r.Context(), "merchant not allowed to call admin route", slog.String("merchant", merchant.ID))
+				writeErrorResponse(w, r.Header.Get(paymenthttp.HeaderContentType), model.ErrorResponse{
+					Code:    http.StatusForbidden,
+					Message: ErrForbidden.Error(),
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withMerchant(r.Context(), merchant)))
+		})
+	}
+}
+
+// adminRoute reports whether the mux pattern, e.g. "GET /admin/routing/weights", is an admin route
+func adminRoute(pattern string) bool {
+	_, path, _ := strings.Cut(pattern, " ")
+	return strings.HasPrefix(path, "/admin/")
+}
+
+// publicGRPCMethods are served without authentication, the gateways send their status updates
+var publicGRPCMethods = map[string]bool{
+	paymentv1.TransactionService_UpdateStatus_FullMethodName: true,
+}
+
+// authenticateGRPC resolves the merchant of the calls from the API key of their authorization metadata,
+// like authenticate does for HTTP
+func (a *merchantAuthenticator) authenticateGRPC(ctx context.Context, method string) (context.Context, error) {
+	if publicGRPCMethods[method] {
+		return ctx, nil
+	}
+
+	merchant, err := a.Authenticate(bearerToken(authorizationMetadata(ctx)))
+	if err != nil {
+		slog.DebugContext(ctx, "grpc: failed to authenticate merchant", slog.String("method", method), slog.Any("error", err))
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return withMerchant(ctx, merchant), nil
+}
+
+// authorizationMetadata returns the authorization metadata of the incoming call, empty when there is none
+func authorizationMetadata(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			return values[0]
+		}
+	}
+
+	return ""
+}
+
+// UnaryInterceptor authenticates the unary calls
+func (a *merchantAuthenticator) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticateGRPC(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// StreamInterceptor authenticates the streaming calls
+func (a *merchantAuthenticator) StreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticateGRPC(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream carries the context of the authenticated merchant
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
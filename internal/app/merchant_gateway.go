@@ -0,0 +1,107 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"go-payment-service/internal/config"
+	"go-payment-service/pkg/model"
+)
+
+// merchantContract is the contract of a merchant with a gateway
+type merchantContract struct {
+	// apiKey authenticates the requests of the merchant to the gateway, the gateway's when empty (platformCredentials)
+	apiKey string
+	// minAmount and maxAmount narrow the amounts the gateway accepts for the merchant, no limit when 0
+	minAmount float64
+	maxAmount float64
+}
+
+// newMerchantContracts returns the enabled contracts of the merchants, by merchant then gateway ID.
+// Merchants without contracts are left out, their transactions are sent to all the gateways.
+func newMerchantContracts(merchants []config.Merchant) map[string]map[string]merchantContract {
+	contracts := make(map[string]map[string]merchantContract, len(merchants))
+
+	for _, m := range merchants {
+		if len(m.Gateways) == 0 {
+			continue
+		}
+
+		// a merchant whose contracts are all disabled has no gateway
+		contracts[m.ID] = make(map[string]merchantContract, len(m.Gateways))
+		for _, gw := range m.Gateways {
+			if gw.IsEnabled() {
+				contracts[m.ID][gw.ID] = merchantContract{apiKey: gw.APIKey, minAmount: gw.MinAmount, maxAmount: gw.MaxAmount}
+			}
+		}
+	}
+
+	return contracts
+}
+
+// validateContracts checks every merchant with enabled contracts keeps at least one of its gateways registered,
+// so a gateway being disabled or removed does not leave a merchant without gateway. The merchants whose contracts
+// are all disabled are left out.
+func validateContracts(contracts map[string]map[string]merchantContract, gateways map[string]PaymentGateway) error {
+	var errs []error
+
+	for _, merchantID := range slices.Sorted(maps.Keys(contracts)) {
+		ids := slices.Sorted(maps.Keys(contracts[merchantID]))
+		if len(ids) > 0 && !slices.ContainsFunc(ids, func(id string) bool { return gateways[id] != nil }) {
+			errs = append(errs, fmt.Errorf("merchant %s: none of its gateways %v is enabled", merchantID, ids))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// apply returns the gateway sending the credentials of the merchant and accepting its amounts only
+func (c merchantContract) apply(gateway PaymentGateway) PaymentGateway {
+	if c.apiKey != "" {
+		if g, ok := gateway.(credentialedGateway); ok {
+			gateway = g.withAPIKey(c.apiKey)
+		}
+	}
+
+	if c.minAmount == 0 && c.maxAmount == 0 {
+		return gateway
+	}
+
+	caps := gateway.Capabilities()
+	if c.minAmount > caps.MinAmount {
+		caps.MinAmount = c.minAmount
+	}
+	if c.maxAmount != 0 && (caps.MaxAmount == 0 || c.maxAmount < caps.MaxAmount) {
+		caps.MaxAmount = c.maxAmount
+	}
+
+	return &limitedGateway{PaymentGateway: gateway, capabilities: caps}
+}
+
+// credentialedGateway is implemented by the gateways able to send the API key of a merchant
+type credentialedGateway interface {
+	// withAPIKey returns a copy of the gateway sending the API key, sharing its HTTP client
+	withAPIKey(apiKey string) PaymentGateway
+}
+
+// limitedGateway narrows the capabilities of a gateway to the limits of a merchant
+type limitedGateway struct {
+	PaymentGateway
+	capabilities model.GatewayCapabilities
+}
+
+// Capabilities declares the transactions the gateway supports for the merchant
+func (g *limitedGateway) Capabilities() model.GatewayCapabilities {
+	return g.capabilities
+}
+
+// BreakerState returns the state of the circuit breaker of the gateway
+func (g *limitedGateway) BreakerState() model.CircuitBreakerState {
+	if r, ok := g.PaymentGateway.(breakerReporter); ok {
+		return r.BreakerState()
+	}
+
+	return model.CircuitBreakerState{State: "unknown"}
+}
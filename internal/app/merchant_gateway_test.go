@@ -0,0 +1,202 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"go-payment-service/internal/config"
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+)
+
+type TestMerchantGatewaySuite struct {
+	suite.Suite
+}
+
+func (suite *TestMerchantGatewaySuite) TestRoute() {
+	disabled := false
+
+	router := newGatewayRouter(map[string]PaymentGateway{
+		"gatewayA": newGatewayAAdapter(paymenthttp.NewResilientHTTPClient(), "http://gateway-a"),
+		"gatewayB": newGatewayBAdapter(paymenthttp.NewResilientHTTPClient(), "http://gateway-b"),
+	}, nil, "gatewayA", "gatewayB")
+	router.setContracts(newMerchantContracts([]config.Merchant{
+		{ID: "merchantA", Gateways: []config.MerchantGateway{{ID: "gatewayB", MinAmount: 10, MaxAmount: 100}}},
+		{ID: "merchantB", Gateways: []config.MerchantGateway{{ID: "gatewayA", Enabled: &disabled}}},
+		{ID: "merchantC"},
+	}))
+
+	testCases := []struct {
+		name     string
+		merchant string
+		amount   float64
+		gateway  string
+		expected []string
+		err      error
+	}{
+		{
+			name:     "gateways of the contracts",
+			merchant: "merchantA",
+			amount:   50,
+			expected: []string{"gatewayB"},
+		},
+		{
+			name:     "above the limit of the merchant",
+			merchant: "merchantA",
+			amount:   500,
+			err:      ErrNotSupported,
+		},
+		{
+			name:     "below the limit of the merchant",
+			merchant: "merchantA",
+			amount:   5,
+			err:      ErrNotSupported,
+		},
+		{
+			name:     "requested gateway without contract",
+			merchant: "merchantA",
+			amount:   50,
+			gateway:  "gatewayA",
+			err:      ErrUnsupportedGateway,
+		},
+		{
+			name:     "contracts disabled",
+			merchant: "merchantB",
+			amount:   50,
+			err:      ErrUnsupportedGateway,
+		},
+		{
+			name:     "merchant without contracts",
+			merchant: "merchantC",
+			amount:   500,
+			expected: []string{"gatewayA", "gatewayB"},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			decision, err := router.Route(model.Transaction{
+				MerchantID:     tc.merchant,
+				Type:           model.Deposit,
+				Amount:         model.Money{Amount: tc.amount, Currency: "EUR"},
+				CardDetails:    model.CardDetails{Number: "4111111111111111"},
+				GatewayDetails: model.GatewayDetails{ID: tc.gateway},
+			})
+
+			suite.ErrorIs(err, tc.err)
+			suite.Equal(tc.expected, decision.Gateways)
+		})
+	}
+}
+
+func (suite *TestMerchantGatewaySuite) TestCredentials() {
+	testCases := []struct {
+		name     string
+		merchant string
+		expected string
+	}{
+		{
+			name:     "credentials of the merchant",
+			merchant: "merchantA",
+			expected: "Bearer merchant-key",
+		},
+		{
+			name:     "credentials of the gateway",
+			merchant: "merchantC",
+			expected: "Bearer gateway-key",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			var authorization, idempotencyKey string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get(paymenthttp.HeaderAuthorization)
+				idempotencyKey = r.Header.Get(paymenthttp.HeaderIdempotencyKey)
+				_ = json.NewEncoder(w).Encode(model.GatewayResponse{TransactionID: "external", Status: model.Pending})
+			}))
+			defer server.Close()
+
+			gateway := newGatewayAAdapter(paymenthttp.NewResilientHTTPClient(), server.URL)
+			gateway.apiKey = "gateway-key"
+
+			router := newGatewayRouter(map[string]PaymentGateway{"gatewayA": gateway}, nil, "gatewayA")
+			router.setContracts(newMerchantContracts([]config.Merchant{
+				{ID: "merchantA", Gateways: []config.MerchantGateway{{ID: "gatewayA", APIKey: "merchant-key"}}},
+			}))
+
+			tx := model.Transaction{
+				ID:          "tx",
+				MerchantID:  tc.merchant,
+				Type:        model.Deposit,
+				Amount:      model.Money{Amount: 10, Currency: "EUR"},
+				CardDetails: model.CardDetails{Number: "4111111111111111"},
+			}
+
+			plan, err := router.plan(tx)
+			suite.Require().NoError(err)
+
+			_, err = plan.gateways["gatewayA"].ProcessTransaction(context.Background(), tx)
+			suite.Require().NoError(err)
+
+			suite.Equal(tc.expected, authorization)
+			suite.Empty(idempotencyKey, "the gateway does not deduplicate requests")
+			suite.Equal("gateway-key", gateway.apiKey, "the gateway keeps its own credentials")
+		})
+	}
+}
+
+func (suite *TestMerchantGatewaySuite) TestRejectedCredentials() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(paymenthttp.HeaderAuthorization) == "Bearer revoked-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(model.GatewayResponse{TransactionID: "external", Status: model.Pending})
+	}))
+	defer server.Close()
+
+	cfg := paymenthttp.DefaultClientConfig("gatewayA")
+	cfg.MaxRetries = 0
+	cfg.BreakerFailures = 1
+
+	gateway := newGatewayAAdapter(paymenthttp.NewResilientHTTPClientWithConfig(cfg), server.URL)
+
+	router := newGatewayRouter(map[string]PaymentGateway{"gatewayA": gateway}, nil, "gatewayA")
+	router.setContracts(newMerchantContracts([]config.Merchant{
+		{ID: "merchantA", Gateways: []config.MerchantGateway{{ID: "gatewayA", APIKey: "revoked-key"}}},
+		{ID: "merchantB", Gateways: []config.MerchantGateway{{ID: "gatewayA", APIKey: "merchant-key"}}},
+	}))
+
+	process := func(merchantID string) error {
+		tx := model.Transaction{
+			ID:          "tx",
+			MerchantID:  merchantID,
+			Type:        model.Deposit,
+			Amount:      model.Money{Amount: 10, Currency: "EUR"},
+			CardDetails: model.CardDetails{Number: "4111111111111111"},
+		}
+
+		plan, err := router.plan(tx)
+		suite.Require().NoError(err)
+
+		_, err = plan.gateways["gatewayA"].ProcessTransaction(context.Background(), tx)
+		return err
+	}
+
+	for range 3 {
+		suite.ErrorIs(process("merchantA"), paymenthttp.ErrCredentialsRejected)
+	}
+
+	suite.Equal("closed", gateway.BreakerState().State)
+	suite.NoError(process("merchantB"), "the merchant's own credentials are not affected")
+}
+
+func TestTestMerchantGatewaySuite(t *testing.T) {
+	suite.Run(t, new(TestMerchantGatewaySuite))
+}
@@ -0,0 +1,265 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"go-payment-service/internal/config"
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+	paymentv1 "go-payment-service/pkg/pb/payment/v1"
+)
+
+type TestMerchantSuite struct {
+	suite.Suite
+	merchants *merchantAuthenticator
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (suite *TestMerchantSuite) SetupTest() {
+	suite.merchants = newMerchantAuthenticator([]config.Merchant{
+		{ID: "merchantA", Name: "Merchant A", APIKeyHash: hashAPIKey("key-a")},
+		{ID: "merchantB", APIKeyHash: hashAPIKey("key-b")},
+		{ID: "operator", APIKeyHash: hashAPIKey("key-admin"), Admin: true},
+	})
+}
+
+func (suite *TestMerchantSuite) TestAuthenticate() {
+	testCases := []struct {
+		name             string
+		merchants        *merchantAuthenticator
+		path             string
+		authorization    string
+		expectedCode     int
+		expectedMerchant string
+	}{
+		{
+			name:             "valid API key",
+			merchants:        suite.merchants,
+			path:             "/gateways",
+			authorization:    "Bearer key-a",
+			expectedCode:     http.StatusOK,
+			expectedMerchant: "merchantA",
+		},
+		{
+			name:         "missing API key",
+			merchants:    suite.merchants,
+			path:         "/gateways",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "invalid API key",
+			merchants:     suite.merchants,
+			path:          "/gateways",
+			authorization: "Bearer key-c",
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "other scheme",
+			merchants:     suite.merchants,
+			path:          "/gateways",
+			authorization: "Basic key-a",
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:         "public route",
+			merchants:    suite.merchants,
+			path:         "/healthz",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:             "admin route",
+			merchants:        suite.merchants,
+			path:             "/admin/routing/weights",
+			authorization:    "Bearer key-admin",
+			expectedCode:     http.StatusOK,
+			expectedMerchant: "operator",
+		},
+		{
+			name:          "admin route without admin role",
+			merchants:     suite.merchants,
+			path:          "/admin/routing/weights",
+			authorization: "Bearer key-a",
+			expectedCode:  http.StatusForbidden,
+		},
+		{
+			name:          "rejected without merchants",
+			merchants:     newMerchantAuthenticator(nil),
+			path:          "/gateways",
+			authorization: "Bearer key-a",
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:         "not authenticated when disabled",
+			path:         "/gateways",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			var merchant model.Merchant
+
+			mux := http.NewServeMux()
+			for _, pattern := range []string{"GET /gateways", "GET /healthz", "GET /admin/routing/weights"} {
+				mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+					merchant = merchantFromContext(r.Context())
+				})
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.authorization != "" {
+				req.Header.Set(paymenthttp.HeaderAuthorization, tc.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			Chain(mux, authenticate(mux, tc.merchants, publicRoutes...)).ServeHTTP(w, req)
+
+			suite.Equal(tc.expectedCode, w.Code)
+			suite.Equal(tc.expectedMerchant, merchant.ID)
+			if tc.expectedCode == http.StatusUnauthorized {
+				suite.Equal("Bearer", w.Header().Get(paymenthttp.HeaderWWWAuthenticate))
+			}
+		})
+	}
+}
+
+func (suite *TestMerchantSuite) TestTransactionOwnership() {
+	repository := newMemoryTransactionRepository()
+	for _, tx := range []model.Transaction{
+		{ID: "tx-a", MerchantID: "merchantA", Type: model.Deposit, Status: model.Pending},
+		{ID: "tx-b", MerchantID: "merchantB", Type: model.Deposit, Status: model.Pending},
+	} {
+		suite.Require().NoError(repository.Create(&tx))
+	}
+
+	service := newTransactionService(&sync.WaitGroup{}, newGatewayRouter(nil, nil), repository, newTransactionBroker(), nil)
+	h := newHandler(service, nil, newHealthChecker())
+	server := Chain(h.mux, authenticate(h.mux, suite.merchants, publicRoutes...))
+
+	testCases := []struct {
+		name         string
+		apiKey       string
+		id           string
+		expectedCode int
+	}{
+		{
+			name:         "own transaction",
+			apiKey:       "key-a",
+			id:           "tx-a",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "transaction of another merchant",
+			apiKey:       "key-a",
+			id:           "tx-b",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "waiting for a transaction of another merchant",
+			apiKey:       "key-b",
+			id:           "tx-a?waitFor=terminal&timeout=1s",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/transactions/"+tc.id, nil)
+			req.Header.Set(paymenthttp.HeaderAuthorization, "Bearer "+tc.apiKey)
+
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+
+			suite.Equal(tc.expectedCode, w.Code)
+		})
+	}
+
+	suite.T().Run("export", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/transactions/export", nil)
+		req.Header.Set(paymenthttp.HeaderAuthorization, "Bearer key-b")
+		req.Header.Set(paymenthttp.HeaderAccept, paymenthttp.MIMETypeNDJSON)
+
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+
+		suite.Require().Equal(http.StatusOK, w.Code)
+
+		var ids []string
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var tx model.Transaction
+			suite.Require().NoError(json.Unmarshal(scanner.Bytes(), &tx))
+			ids = append(ids, tx.ID)
+		}
+		suite.Equal([]string{"tx-b"}, ids)
+	})
+}
+
+func (suite *TestMerchantSuite) TestAuthenticateGRPC() {
+	testCases := []struct {
+		name             string
+		method           string
+		authorization    string
+		expectedCode     codes.Code
+		expectedMerchant string
+	}{
+		{
+			name:             "valid API key",
+			method:           paymentv1.TransactionService_GetByID_FullMethodName,
+			authorization:    "Bearer key-b",
+			expectedCode:     codes.OK,
+			expectedMerchant: "merchantB",
+		},
+		{
+			name:         "missing API key",
+			method:       paymentv1.TransactionService_Deposit_FullMethodName,
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "gateway status update",
+			method:       paymentv1.TransactionService_UpdateStatus_FullMethodName,
+			expectedCode: codes.OK,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tc.authorization))
+			}
+
+			var merchant model.Merchant
+			_, err := suite.merchants.UnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method},
+				func(ctx context.Context, req any) (any, error) {
+					merchant = merchantFromContext(ctx)
+					return nil, nil
+				})
+
+			suite.Equal(tc.expectedCode, status.Code(err))
+			suite.Equal(tc.expectedMerchant, merchant.ID)
+		})
+	}
+}
+
+func TestTestMerchantSuite(t *testing.T) {
+	suite.Run(t, new(TestMerchantSuite))
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sony/gobreaker"
+
+	"go-payment-service/pkg/model"
+)
+
+// breakerStates are the circuit breaker states reported by the state gauge
+var breakerStates = []string{gobreaker.StateClosed.String(), gobreaker.StateHalfOpen.String(), gobreaker.StateOpen.String()}
+
+// metrics holds the Prometheus metrics of the service, on its own registry so that servers don't share them
+type metrics struct {
+	registry *prometheus.Registry
+
+	transactions   *prometheus.CounterVec
+	processing     *prometheus.HistogramVec
+	gatewayLatency *prometheus.HistogramVec
+	callbacks      *prometheus.CounterVec
+	inFlight       prometheus.Gauge
+	httpRequests   *prometheus.CounterVec
+	httpDuration   *prometheus.HistogramVec
+}
+
+// newMetrics creates the metrics of the service, the state and retries of the gateways registered
+// on the router being read on every scrape
+func newMetrics(router *gatewayRouter) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "payment_transactions_total",
+			Help: "Transactions entering a status, by type, status and gateway.",
+		}, []string{"type", "status", "gateway"}),
+		processing: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "payment_transaction_processing_seconds",
+			Help:    "Time from the creation of a transaction to its terminal status, by type and status.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		}, []string{"type", "status"}),
+		gatewayLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "payment_gateway_request_duration_seconds",
+			Help:    "Time for a gateway to answer a transaction, retries included, by gateway and routing outcome.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"gateway", "outcome"}),
+		callbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "payment_callbacks_total",
+			Help: "Status updates received from the gateways, by outcome.",
+		}, []string{"outcome"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "payment_transactions_in_flight",
+			Help: "Transactions being sent to the gateways.",
+		}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by route and status code.",
+		}, []string{"route", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time to serve HTTP requests, by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route"}),
+	}
+
+	m.registry.MustRegister(
+		m.transactions,
+		m.processing,
+		m.gatewayLatency,
+		m.callbacks,
+		m.inFlight,
+		m.httpRequests,
+		m.httpDuration,
+		&gatewayCollector{router: router},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveTransaction counts the transaction entering its status and, once terminal, observes its processing time
+func (m *metrics) ObserveTransaction(tx model.Transaction) {
+	m.transactions.WithLabelValues(string(tx.Type), string(tx.Status), tx.GatewayDetails.ID).Inc()
+
+	if tx.Status.IsTerminal() && !tx.CreatedAt.IsZero() {
+		m.processing.WithLabelValues(string(tx.Type), string(tx.Status)).Observe(time.Since(tx.CreatedAt).Seconds())
+	}
+}
+
+// instrument counts the HTTP requests and observes their duration by route, the pattern they matched
+func (m *metrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		wrapped := &wrappedWriter{w, http.StatusOK}
+
+		next.ServeHTTP(wrapped, r)
+
+		// set by the mux on the request, none when no route matched
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+
+		m.httpRequests.WithLabelValues(route, strconv.Itoa(wrapped.statusCode)).Inc()
+		m.httpDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// gatewayCollector reports the circuit breaker state and retries of the registered gateways when scraped,
+// so gateways added or removed by a reload are reported without registration
+type gatewayCollector struct {
+	router *gatewayRouter
+}
+
+var (
+	breakerStateDesc = prometheus.NewDesc(
+		"payment_gateway_circuit_breaker_state",
+		"Circuit breaker state of the gateway, 1 for the current state.",
+		[]string{"gateway", "state"}, nil,
+	)
+	gatewayRetriesDesc = prometheus.NewDesc(
+		"payment_gateway_retries_total",
+		"Requests to the gateway retried by its HTTP client, reset when the gateway is reloaded with new settings.",
+		[]string{"gateway"}, nil,
+	)
+)
+
+func (c *gatewayCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- breakerStateDesc
+	ch <- gatewayRetriesDesc
+}
+
+func (c *gatewayCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, id := range c.router.ordered() {
+		gateway, exists := c.router.Gateway(id)
+		if !exists {
+			continue
+		}
+
+		if r, ok := gateway.(breakerReporter); ok {
+			current := r.BreakerState().State
+			for _, state := range breakerStates {
+				value := 0.0
+				if state == current {
+					value = 1
+				}
+				ch <- prometheus.MustNewConstMetric(breakerStateDesc, prometheus.GaugeValue, value, id, state)
+			}
+		}
+
+		if r, ok := gateway.(retryReporter); ok {
+			ch <- prometheus.MustNewConstMetric(gatewayRetriesDesc, prometheus.CounterValue, float64(r.Retries()), id)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/suite"
+
+	"go-payment-service/pkg/model"
+)
+
+type TestMetricsSuite struct {
+	suite.Suite
+}
+
+// scrape returns the metrics exposed by the handler
+func (suite *TestMetricsSuite) scrape(m *metrics) string {
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	b, err := io.ReadAll(w.Body)
+	suite.Require().NoError(err)
+
+	return string(b)
+}
+
+func (suite *TestMetricsSuite) TestTransactionMetrics() {
+	unreachable := fmt.Errorf("failed to send HTTP request: %w", gobreaker.ErrOpenState)
+	gateways := map[string]PaymentGateway{
+		"gatewayA": &stubGateway{err: unreachable, breaker: gobreaker.StateHalfOpen},
+		"gatewayB": &stubGateway{},
+	}
+
+	webhooks := newWebhookService(newMemoryWebhookRepository(), []byte("secret"))
+	service := newTransactionService(&sync.WaitGroup{}, newGatewayRouter(gateways, nil, "gatewayA", "gatewayB"), newMemoryTransactionRepository(), newTransactionBroker(), webhooks)
+
+	_, err := service.Deposit(context.Background(), model.DepositRequest{})
+	suite.Require().NoError(err)
+
+	suite.Error(service.UpdateStatus(context.Background(), model.TransactionStatusUpdate{TransactionID: "unknown", Status: model.Succeeded}))
+
+	metrics := suite.scrape(service.metrics)
+
+	for _, expected := range []string{
+		`payment_transactions_total{gateway="",status="pending",type="deposit"} 1`,
+		`payment_transactions_total{gateway="gatewayB",status="succeeded",type="deposit"} 1`,
+		`payment_transaction_processing_seconds_count{status="succeeded",type="deposit"} 1`,
+		`payment_gateway_request_duration_seconds_count{gateway="gatewayA",outcome="failed_over"} 1`,
+		`payment_gateway_request_duration_seconds_count{gateway="gatewayB",outcome="processed"} 1`,
+		`payment_callbacks_total{outcome="not_found"} 1`,
+		`payment_transactions_in_flight 0`,
+		`payment_gateway_circuit_breaker_state{gateway="gatewayA",state="half-open"} 1`,
+		`payment_gateway_circuit_breaker_state{gateway="gatewayB",state="closed"} 1`,
+		`payment_gateway_circuit_breaker_state{gateway="gatewayB",state="open"} 0`,
+	} {
+		suite.Contains(metrics, expected)
+	}
+}
+
+func (suite *TestMetricsSuite) TestHTTPMetrics() {
+	m := newMetrics(newGatewayRouter(nil, nil))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /transactions/{id}", http.NotFound)
+	handler := m.instrument(mux)
+
+	for _, path := range []string{"/transactions/1", "/transactions/2", "/unknown"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	metrics := suite.scrape(m)
+
+	suite.Contains(metrics, `http_requests_total{code="404",route="GET /transactions/{id}"} 2`, "requests are labelled by route, not path")
+	suite.Contains(metrics, `http_requests_total{code="404",route="unmatched"} 1`)
+	suite.Contains(metrics, `http_request_duration_seconds_count{route="GET /transactions/{id}"} 2`)
+}
+
+func TestTestMetricsSuite(t *testing.T) {
+	suite.Run(t, new(TestMetricsSuite))
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+)
+
+// Middleware wraps a handler, e.g. to log or limit its requests
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps the handler with the middlewares, the first one being the outermost
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+
+	return h
+}
+
+// startedWriter records whether the response was started, i.e. its header written
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *startedWriter) WriteHeader(statusCode int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *startedWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original ResponseWriter, allowing http.ResponseController to reach it
+func (w *startedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Recover recovers from the panics of the handlers, logging them with their stack, and responds with a
+// 500 Internal Server Error encoded like the other errors. When the response was already started, the
+// connection is closed instead so the client does not take a truncated response for a complete one.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &startedWriter{ResponseWriter: w}
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// the handler aborted the response on purpose, let the server close the connection
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			slog.ErrorContext(r.Context(), "handler panicked", slog.Any("panic", recovered), slog.String("stack", string(debug.Stack())))
+
+			if sw.started {
+				panic(http.ErrAbortHandler)
+			}
+
+			writeErrorResponse(w, r.Header.Get(paymenthttp.HeaderContentType), model.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: http.StatusText(http.StatusInternalServerError),
+			})
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// LimitBody limits the size of the request bodies to the limit of the route they match on the mux, the
+// default limit for the routes without one. Reading past it fails with an *http.MaxBytesError, which the
+// handlers answer with 413 Request Entity Too Large.
+func LimitBody(mux *http.ServeMux, limit int64, routeLimits map[string]int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			routeLimit := limit
+			if _, pattern := mux.Handler(r); pattern != "" {
+				if l, ok := routeLimits[pattern]; ok {
+					routeLimit = l
+				}
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, routeLimit)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// securityHeaders are set on every response, the API serves nothing to be rendered or framed by browsers
+var securityHeaders = map[string]string{
+	paymenthttp.HeaderContentTypeOptions:    "nosniff",
+	paymenthttp.HeaderFrameOptions:          "DENY",
+	paymenthttp.HeaderContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+	paymenthttp.HeaderReferrerPolicy:        "no-referrer",
+}
+
+// SecurityHeaders sets the security headers on the responses, and HSTS on the ones served over TLS
+func SecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range securityHeaders {
+			w.Header().Set(name, value)
+		}
+
+		if r.TLS != nil {
+			w.Header().Set(paymenthttp.HeaderStrictTransportSecurity, "max-age=63072000; includeSubDomains")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clearDeadlines lifts the read and write timeouts of the server for a long-lived response, e.g. a stream.
+// The read deadline is lifted too as the server cancels the request context when it is reached.
+func clearDeadlines(rc *http.ResponseController) {
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+}
+
+// extendDeadlines moves the read and write timeouts of the server for a response taking up to d, e.g. a long poll
+func extendDeadlines(rc *http.ResponseController, d time.Duration) {
+	deadline := time.Now().Add(d)
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline)
+}
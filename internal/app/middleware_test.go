@@ -0,0 +1,240 @@
+package app
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+)
+
+type TestMiddlewareSuite struct {
+	suite.Suite
+}
+
+func (suite *TestMiddlewareSuite) TestChain() {
+	var order []string
+	middleware := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), middleware("outer"), middleware("inner"))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	suite.Equal([]string{"outer", "inner", "handler"}, order)
+}
+
+func (suite *TestMiddlewareSuite) TestRecover() {
+	testCases := []struct {
+		name        string
+		contentType string
+		decode      func(r io.Reader, v any) error
+	}{
+		{
+			name:        "json",
+			contentType: paymenthttp.MIMETypeJSON,
+			decode:      func(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) },
+		},
+		{
+			name:        "xml",
+			contentType: paymenthttp.MIMETypeXML,
+			decode:      func(r io.Reader, v any) error { return xml.NewDecoder(r).Decode(v) },
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			}), RequestID, Recover)
+
+			req := httptest.NewRequest(http.MethodPost, "/deposit", nil)
+			req.Header.Set(paymenthttp.HeaderContentType, tc.contentType)
+
+			w := httptest.NewRecorder()
+			suite.NotPanics(func() { h.ServeHTTP(w, req) })
+
+			suite.Equal(http.StatusInternalServerError, w.Code)
+
+			var er model.ErrorResponse
+			suite.Require().NoError(tc.decode(w.Body, &er))
+			suite.Equal(http.StatusInternalServerError, er.Code)
+			suite.Equal(w.Header().Get(paymenthttp.HeaderRequestID), er.RequestID)
+		})
+	}
+}
+
+func (suite *TestMiddlewareSuite) TestRecoverStartedResponse() {
+	testCases := []struct {
+		name  string
+		given http.HandlerFunc
+	}{
+		{
+			name: "panic after the response started",
+			given: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, "partial")
+				panic("boom")
+			},
+		},
+		{
+			name: "aborted response",
+			given: func(w http.ResponseWriter, r *http.Request) {
+				panic(http.ErrAbortHandler)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			suite.PanicsWithValue(http.ErrAbortHandler, func() {
+				Recover(tc.given).ServeHTTP(httptest.NewRecorder(), req)
+			})
+		})
+	}
+}
+
+func (suite *TestMiddlewareSuite) TestLimitBody() {
+	testCases := []struct {
+		name         string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "default limit exceeded",
+			path:         "/deposit",
+			body:         `{"orderId":"` + strings.Repeat("1", 64) + `"}`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "route limit",
+			path:         "/withdrawal",
+			body:         `{"orderId":"` + strings.Repeat("1", 64) + `"`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			h := newHandler(nil, nil, newHealthChecker())
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+
+			w := httptest.NewRecorder()
+			Chain(h.mux, LimitBody(h.mux, 16, map[string]int64{"POST /withdrawal": 1024})).ServeHTTP(w, req)
+
+			suite.Equal(tc.expectedCode, w.Code)
+
+			var er model.ErrorResponse
+			suite.Require().NoError(json.NewDecoder(w.Body).Decode(&er))
+			suite.Equal(tc.expectedCode, er.Code)
+		})
+	}
+}
+
+func (suite *TestMiddlewareSuite) TestSecurityHeaders() {
+	testCases := []struct {
+		name         string
+		tls          bool
+		expectedHSTS bool
+	}{
+		{
+			name: "plain HTTP",
+		},
+		{
+			name:         "TLS",
+			tls:          true,
+			expectedHSTS: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+
+			w := httptest.NewRecorder()
+			SecurityHeaders(http.NotFoundHandler()).ServeHTTP(w, req)
+
+			suite.Equal("nosniff", w.Header().Get(paymenthttp.HeaderContentTypeOptions))
+			suite.Equal("DENY", w.Header().Get(paymenthttp.HeaderFrameOptions))
+			suite.NotEmpty(w.Header().Get(paymenthttp.HeaderContentSecurityPolicy))
+			suite.Equal(tc.expectedHSTS, w.Header().Get(paymenthttp.HeaderStrictTransportSecurity) != "")
+		})
+	}
+}
+
+func (suite *TestMiddlewareSuite) TestClearDeadlines() {
+	testCases := []struct {
+		name     string
+		clear    bool
+		expected bool
+	}{
+		{
+			name: "response past the write timeout is lost",
+		},
+		{
+			name:     "long-lived response",
+			clear:    true,
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.clear {
+					clearDeadlines(http.NewResponseController(w))
+				}
+
+				time.Sleep(300 * time.Millisecond)
+
+				if r.Context().Err() == nil {
+					_, _ = io.WriteString(w, "done")
+				}
+			}))
+			server.Config.ReadTimeout = 100 * time.Millisecond
+			server.Config.WriteTimeout = 100 * time.Millisecond
+			server.Start()
+			defer server.Close()
+
+			resp, err := server.Client().Get(server.URL)
+			if !tc.expected {
+				suite.Error(err)
+				return
+			}
+			suite.Require().NoError(err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			suite.Require().NoError(err)
+			suite.Equal("done", string(body))
+		})
+	}
+}
+
+func TestTestMiddlewareSuite(t *testing.T) {
+	suite.Run(t, new(TestMiddlewareSuite))
+}
@@ -0,0 +1,296 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+
+	"go-payment-service/internal/config"
+)
+
+// Reload applies the gateways, their client settings, the routing rules, priority and weights and the gateway
+// contracts of the merchants of the configuration without restart. They are swapped atomically once all of them are valid, the transactions being processed keep
+// the gateways they were routed with. The gateways whose settings did not change keep their adapter, and so
+// their circuit breaker and connections, the idle connections of the replaced ones are closed. The other settings
+// need a restart, their changes are logged and ignored. Reloads leaving a merchant without any of its gateways
+// are rejected, as are reloads once the server is shutting down.
+func (s *server) Reload(cfg config.Config) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if s.draining.Load() {
+		return errors.New("failed to reload: server is shutting down")
+	}
+
+	changes, restart := diffConfig(s.cfg, cfg)
+
+	rules, err := loadRoutingRulesFile(cfg.Routing.RulesFile, gatewayIDs(cfg.Gateways))
+	if err != nil {
+		return fmt.Errorf("failed to reload routing rules: %w", err)
+	}
+	if cfg.Routing.RulesFile != "" && cfg.Routing.RulesFile == s.cfg.Routing.RulesFile && !reflect.DeepEqual(rules, s.rules) {
+		changes = append(changes, fmt.Sprintf("routing rules: %s changed", cfg.Routing.RulesFile))
+	}
+
+	gateways, err := s.reloadGateways(cfg.Gateways)
+	if err != nil {
+		return fmt.Errorf("failed to reload gateways: %w", err)
+	}
+
+	weights := gatewayWeights(cfg.Routing.Weights)
+	weightsChanged := !reflect.DeepEqual(cfg.Routing.Weights, s.cfg.Routing.Weights)
+
+	merchants := reloadMerchants(s.cfg.Merchants, cfg.Merchants)
+	contracts := newMerchantContracts(merchants)
+
+	// the weights set through the API are kept unless the configured ones changed
+	err = s.router.update(func(t routingTable) (routingTable, error) {
+		if err := validateContracts(contracts, gateways); err != nil {
+			return t, fmt.Errorf("failed to reload merchant gateways: %w", err)
+		}
+
+		t.gateways, t.rules, t.priority = gateways, rules, cfg.Routing.Priority
+		t.callbackSecrets = callbackSecrets(cfg.Gateways, s.emulatorSecret)
+		t.contracts = contracts
+		if weightsChanged {
+			if err := validateWeights(weights, gateways); err != nil {
+				return t, fmt.Errorf("failed to reload routing weights: %w", err)
+			}
+
+			t.weights = slices.Clone(weights)
+		}
+
+		return t, nil
+	})
+	if err != nil {
+		closeReplaced(gateways, s.gateways)
+		return err
+	}
+
+	closeReplaced(s.gateways, gateways)
+	s.gateways, s.rules = gateways, rules
+	s.cfg.Gateways, s.cfg.Routing, s.cfg.Merchants = cfg.Gateways, cfg.Routing, merchants
+
+	for _, change := range restart {
+		slog.Warn("server: configuration change ignored, restart required", slog.String("change", change))
+	}
+
+	if len(changes) == 0 {
+		slog.Info("server: configuration reloaded, no change")
+		return nil
+	}
+
+	for _, change := range changes {
+		slog.Info("server: configuration reloaded", slog.String("change", change))
+	}
+
+	return nil
+}
+
+// reloadGateways creates the adapters of the enabled gateways, reusing the current adapter of the gateways
+// whose configuration did not change
+func (s *server) reloadGateways(gateways []config.Gateway) (map[string]PaymentGateway, error) {
+	current := make(map[string]config.Gateway, len(s.cfg.Gateways))
+	for _, gw := range s.cfg.Gateways {
+		current[gw.ID] = gw
+	}
+
+	adapters := make(map[string]PaymentGateway, len(gateways))
+
+	for _, gw := range gateways {
+		if !gw.IsEnabled() {
+			continue
+		}
+
+		if adapter, exists := s.gateways[gw.ID]; exists && reflect.DeepEqual(current[gw.ID], gw) {
+			adapters[gw.ID] = adapter
+			continue
+		}
+
+		adapter, err := newGateway(gw, s.emulatorURL)
+		if err != nil {
+			return nil, err
+		}
+
+		adapters[gw.ID] = adapter
+	}
+
+	return adapters, nil
+}
+
+// reloadMerchants returns the current merchants with their gateway contracts of the updated configuration.
+// Adding or removing merchants needs a restart, so the merchants missing from it keep their contracts.
+func reloadMerchants(current, updated []config.Merchant) []config.Merchant {
+	contracts := make(map[string][]config.MerchantGateway, len(updated))
+	for _, m := range updated {
+		contracts[m.ID] = m.Gateways
+	}
+
+	merchants := slices.Clone(current)
+	for i, m := range merchants {
+		if gateways, exists := contracts[m.ID]; exists {
+			merchants[i].Gateways = gateways
+		}
+	}
+
+	return merchants
+}
+
+// closeReplaced closes the idle connections of the adapters no longer used by the gateways.
+// The transactions still sent through them open new connections, closed by the gateways once idle.
+func closeReplaced(adapters, gateways map[string]PaymentGateway) {
+	for id, adapter := range adapters {
+		if gateways[id] != adapter {
+			closeIdleConnections(adapter)
+		}
+	}
+}
+
+// diffConfig describes the changes between the configurations, the ones applied by Reload
+// and the ones requiring a restart
+func diffConfig(current, updated config.Config) (changes, restart []string) {
+	changes = append(changes, diffGateways(current.Gateways, updated.Gateways)...)
+	changes = append(changes, diffContracts(current.Merchants, updated.Merchants)...)
+
+	routing := []struct {
+		name     string
+		old, new any
+	}{
+		{"routing.rulesFile", current.Routing.RulesFile, updated.Routing.RulesFile},
+		{"routing.priority", current.Routing.Priority, updated.Routing.Priority},
+		{"routing.weights", current.Routing.Weights, updated.Routing.Weights},
+	}
+	for _, f := range routing {
+		if !reflect.DeepEqual(f.old, f.new) {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", f.name, f.old, f.new))
+		}
+	}
+
+	static := []struct {
+		name     string
+		old, new any
+	}{
+		{"env", current.Env, updated.Env},
+		{"http", current.HTTP, updated.HTTP},
+		{"grpc", current.GRPC, updated.GRPC},
+		{"processing", current.Processing, updated.Processing},
+		{"callbackUrl", current.CallbackURL, updated.CallbackURL},
+		{"webhooks", current.Webhooks, updated.Webhooks},
+		{"routing.fingerprintSecret", current.Routing.FingerprintSecret, updated.Routing.FingerprintSecret},
+		{"reload", current.Reload, updated.Reload},
+		{"shutdown", current.Shutdown, updated.Shutdown},
+		{"tracing", current.Tracing, updated.Tracing},
+		{"merchants", merchantAccounts(current.Merchants), merchantAccounts(updated.Merchants)},
+	}
+	for _, f := range static {
+		if !reflect.DeepEqual(f.old, f.new) {
+			restart = append(restart, f.name)
+		}
+	}
+
+	return changes, restart
+}
+
+// diffGateways describes the gateways added, removed, enabled or disabled and the changes of their settings,
+// without revealing their API keys
+func diffGateways(current, updated []config.Gateway) []string {
+	var changes []string
+
+	previous := make(map[string]config.Gateway, len(current))
+	for _, gw := range current {
+		previous[gw.ID] = gw
+	}
+
+	for _, gw := range updated {
+		before, exists := previous[gw.ID]
+		delete(previous, gw.ID)
+
+		switch {
+		case !exists:
+			changes = append(changes, fmt.Sprintf("gateway %s: added", gw.ID))
+			continue
+		case before.IsEnabled() && !gw.IsEnabled():
+			changes = append(changes, fmt.Sprintf("gateway %s: disabled", gw.ID))
+		case !before.IsEnabled() && gw.IsEnabled():
+			changes = append(changes, fmt.Sprintf("gateway %s: enabled", gw.ID))
+		}
+
+		fields := []struct {
+			name     string
+			old, new any
+		}{
+			{"kind", before.Kind, gw.Kind},
+			{"endpoint", before.Endpoint, gw.Endpoint},
+			{"credentials", before.Credentials, gw.Credentials},
+			{"callbackCredentials", before.CallbackCredentials, gw.CallbackCredentials},
+			{"timeout", before.Timeout, gw.Timeout},
+			{"maxRetries", optional(before.MaxRetries), optional(gw.MaxRetries)},
+			{"breakerFailures", before.BreakerFailures, gw.BreakerFailures},
+			{"breakerTimeout", before.BreakerTimeout, gw.BreakerTimeout},
+			{"maxConns", before.MaxConns, gw.MaxConns},
+		}
+		for _, f := range fields {
+			if f.old != f.new {
+				changes = append(changes, fmt.Sprintf("gateway %s: %s %v -> %v", gw.ID, f.name, f.old, f.new))
+			}
+		}
+
+		if before.Credentials == gw.Credentials && before.APIKey != gw.APIKey {
+			changes = append(changes, fmt.Sprintf("gateway %s: API key rotated", gw.ID))
+		}
+		if before.CallbackCredentials == gw.CallbackCredentials && before.CallbackSecret != gw.CallbackSecret {
+			changes = append(changes, fmt.Sprintf("gateway %s: callback secret rotated", gw.ID))
+		}
+	}
+
+	removed := make([]string, 0, len(previous))
+	for id := range previous {
+		removed = append(removed, id)
+	}
+	slices.Sort(removed)
+
+	for _, id := range removed {
+		changes = append(changes, fmt.Sprintf("gateway %s: removed", id))
+	}
+
+	return changes
+}
+
+// diffContracts describes the merchants whose gateway contracts changed, without revealing their API keys
+func diffContracts(current, updated []config.Merchant) []string {
+	var changes []string
+
+	previous := make(map[string][]config.MerchantGateway, len(current))
+	for _, m := range current {
+		previous[m.ID] = m.Gateways
+	}
+
+	for _, m := range updated {
+		if before, exists := previous[m.ID]; exists && !reflect.DeepEqual(before, m.Gateways) {
+			changes = append(changes, fmt.Sprintf("merchant %s: gateways changed", m.ID))
+		}
+	}
+
+	return changes
+}
+
+// merchantAccounts returns the merchants without their gateway contracts, the settings requiring a restart
+func merchantAccounts(merchants []config.Merchant) []config.Merchant {
+	accounts := slices.Clone(merchants)
+	for i := range accounts {
+		accounts[i].Gateways = nil
+	}
+
+	return accounts
+}
+
+// optional returns the value, or "default" when not set
+func optional[T any](v *T) any {
+	if v == nil {
+		return "default"
+	}
+
+	return *v
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"go-payment-service/internal/config"
+	"go-payment-service/pkg/model"
+)
+
+type TestReloadSuite struct {
+	suite.Suite
+	server *server
+}
+
+func (suite *TestReloadSuite) SetupTest() {
+	suite.server = NewServer(config.Default()).(*server)
+	suite.Require().NoError(suite.server.err)
+}
+
+func (suite *TestReloadSuite) TearDownTest() {
+	suite.server.emulator.Close()
+}
+
+func (suite *TestReloadSuite) TestReload() {
+	gatewayA, _ := suite.server.router.Gateway("gatewayA")
+	gatewayB, _ := suite.server.router.Gateway("gatewayB")
+
+	disabled := false
+	cfg := config.Default()
+	cfg.Gateways[0].Enabled = &disabled
+	cfg.Gateways[1].Timeout = config.Duration(2 * time.Second)
+	cfg.Routing.Weights = []config.GatewayWeight{{GatewayID: "gatewayB", Weight: 100}}
+
+	suite.Require().NoError(suite.server.Reload(cfg))
+
+	_, exists := suite.server.router.Gateway("gatewayA")
+	suite.False(exists, "disabled gateways are unregistered")
+
+	reloadedB, _ := suite.server.router.Gateway("gatewayB")
+	suite.NotSame(gatewayB, reloadedB, "gateways with new settings get a new adapter")
+	suite.Equal([]model.GatewayWeight{{GatewayID: "gatewayB", Weight: 100}}, suite.server.router.Weights())
+
+	// enabling gatewayA again keeps the adapter of gatewayB, whose settings are unchanged
+	cfg.Gateways[0].Enabled = nil
+
+	suite.Require().NoError(suite.server.Reload(cfg))
+
+	reloadedA, _ := suite.server.router.Gateway("gatewayA")
+	suite.NotSame(gatewayA, reloadedA)
+
+	unchangedB, _ := suite.server.router.Gateway("gatewayB")
+	suite.Same(reloadedB, unchangedB)
+}
+
+func (suite *TestReloadSuite) TestReloadClosesReplacedAdapters() {
+	replaced, kept := &stubGateway{}, &stubGateway{}
+	suite.server.gateways = map[string]PaymentGateway{"gatewayA": kept, "gatewayB": replaced}
+	suite.server.router.Reload(suite.server.gateways, nil)
+
+	cfg := config.Default()
+	cfg.Gateways[1].Timeout = config.Duration(2 * time.Second)
+	cfg.Routing.Weights = []config.GatewayWeight{{GatewayID: "gatewayA", Weight: 50}, {GatewayID: "gatewayB", Weight: 50}}
+
+	suite.Require().NoError(suite.server.Reload(cfg))
+
+	suite.True(replaced.idleClosed)
+	suite.False(kept.idleClosed)
+
+	// the gateways and weights are swapped in a single table
+	table := suite.server.router.table.Load()
+	suite.Same(kept, table.gateways["gatewayA"])
+	suite.NotSame(replaced, table.gateways["gatewayB"])
+	suite.Equal(gatewayWeights(cfg.Routing.Weights), table.weights)
+}
+
+func (suite *TestReloadSuite) TestReloadContracts() {
+	suite.server.cfg.Merchants = []config.Merchant{
+		{ID: "merchantA", Gateways: []config.MerchantGateway{{ID: "gatewayA"}}},
+		{ID: "merchantB", Gateways: []config.MerchantGateway{{ID: "gatewayA"}}},
+	}
+	suite.server.router.setContracts(newMerchantContracts(suite.server.cfg.Merchants))
+
+	cfg := config.Default()
+	cfg.Merchants = []config.Merchant{
+		{ID: "merchantA", Gateways: []config.MerchantGateway{{ID: "gatewayB"}}},
+		{ID: "merchantC", Gateways: []config.MerchantGateway{{ID: "gatewayB"}}},
+	}
+
+	suite.Require().NoError(suite.server.Reload(cfg))
+
+	suite.Equal([]string{"gatewayB"}, suite.server.router.forMerchant("merchantA").ordered(), "contracts are rebuilt")
+	suite.Equal([]string{"gatewayA"}, suite.server.router.forMerchant("merchantB").ordered(), "removing a merchant needs a restart")
+	suite.Len(suite.server.router.forMerchant("merchantC").ordered(), 2, "adding a merchant needs a restart")
+	suite.Equal([]string{"merchantA", "merchantB"}, []string{suite.server.cfg.Merchants[0].ID, suite.server.cfg.Merchants[1].ID})
+}
+
+func (suite *TestReloadSuite) TestReloadWhileDraining() {
+	suite.server.draining.Store(true)
+
+	suite.ErrorContains(suite.server.Reload(config.Default()), "server is shutting down")
+}
+
+func (suite *TestReloadSuite) TestReloadInvalid() {
+	gatewayA, _ := suite.server.router.Gateway("gatewayA")
+
+	testCases := []struct {
+		name        string
+		given       func(cfg *config.Config)
+		expectedErr string
+	}{
+		{
+			name: "weights of a disabled gateway",
+			given: func(cfg *config.Config) {
+				disabled := false
+				cfg.Gateways[1].Enabled = &disabled
+				cfg.Routing.Weights = []config.GatewayWeight{{GatewayID: "gatewayB", Weight: 100}}
+			},
+			expectedErr: "failed to reload routing weights",
+		},
+		{
+			name: "unknown kind",
+			given: func(cfg *config.Config) {
+				cfg.Gateways[0].Kind = "gatewayC"
+			},
+			expectedErr: `unknown kind "gatewayC"`,
+		},
+		{
+			name: "invalid routing rules",
+			given: func(cfg *config.Config) {
+				path := filepath.Join(suite.T().TempDir(), "rules.json")
+				suite.Require().NoError(os.WriteFile(path, []byte(`{"rules": [{"name": "z", "gateway": "gatewayZ"}]}`), 0o600))
+				cfg.Routing.RulesFile = path
+			},
+			expectedErr: "failed to reload routing rules",
+		},
+		{
+			name: "merchant left without gateway",
+			given: func(cfg *config.Config) {
+				disabled := false
+				cfg.Gateways[0].Enabled = &disabled
+				cfg.Merchants = []config.Merchant{{ID: "merchantA", Gateways: []config.MerchantGateway{{ID: "gatewayA"}}}}
+				suite.server.cfg.Merchants = cfg.Merchants
+			},
+			expectedErr: "merchant merchantA: none of its gateways [gatewayA] is enabled",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			cfg := config.Default()
+			tc.given(&cfg)
+
+			suite.ErrorContains(suite.server.Reload(cfg), tc.expectedErr)
+
+			// nothing changed
+			current, _ := suite.server.router.Gateway("gatewayA")
+			suite.Same(gatewayA, current)
+			suite.Len(suite.server.router.ordered(), 2)
+			suite.Empty(suite.server.router.Weights())
+		})
+	}
+}
+
+func (suite *TestReloadSuite) TestDiffConfig() {
+	old := config.Default()
+	old.Gateways[0].APIKey = "old"
+
+	retries := uint64(1)
+	disabled := false
+	updated := config.Default()
+	updated.Gateways[0].APIKey = "updated"
+	updated.Gateways[0].MaxRetries = &retries
+	updated.Gateways[1].Enabled = &disabled
+	updated.Gateways = append(updated.Gateways, config.Gateway{ID: "gatewayC", Kind: "gatewayA"})
+	updated.Routing.Priority = []string{"gatewayB", "gatewayA"}
+	updated.HTTP.Port = "8000"
+
+	old.Merchants = []config.Merchant{{ID: "merchantA", Gateways: []config.MerchantGateway{{ID: "gatewayA"}}}}
+	updated.Merchants = []config.Merchant{{ID: "merchantA", Gateways: []config.MerchantGateway{{ID: "gatewayB"}}}}
+
+	changes, restart := diffConfig(old, updated)
+
+	suite.Equal([]string{
+		"gateway gatewayA: maxRetries default -> 1",
+		"gateway gatewayA: API key rotated",
+		"gateway gatewayB: disabled",
+		"gateway gatewayC: added",
+		"merchant merchantA: gateways changed",
+		"routing.priority: [gatewayA gatewayB] -> [gatewayB gatewayA]",
+	}, changes)
+	suite.Equal([]string{"http"}, restart, "the contracts of the merchants are reloaded")
+
+	changes, _ = diffConfig(updated, old)
+	suite.Contains(changes, "gateway gatewayC: removed")
+}
+
+func TestTestReloadSuite(t *testing.T) {
+	suite.Run(t, new(TestReloadSuite))
+}
@@ -1,23 +1,59 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"go-payment-service/pkg/model"
 )
 
+// ErrTransactionNotFound is returned when a transaction does not exist in the repository
+var ErrTransactionNotFound = errors.New("transaction not found")
+
 // TransactionRepository defines the methods for transaction data access
 type TransactionRepository interface {
 	Create(tx *model.Transaction) error
 	GetByID(id string) (*model.Transaction, error)
 	GetByExternalID(externalID string) (*model.Transaction, error)
 	List() []*model.Transaction
+	Each(filter TransactionFilter, fn func(tx model.Transaction) error) error
 	Update(tx *model.Transaction) error
+	// Ping reports whether the repository is reachable
+	Ping(ctx context.Context) error
+}
+
+// TransactionFilter narrows down the transactions iterated by the repository.
+// Zero values match any transaction.
+type TransactionFilter struct {
+	From      time.Time // inclusive
+	To        time.Time // exclusive
+	Status    model.TransactionStatus
+	GatewayID string
+}
+
+// Match reports whether the transaction satisfies the filter.
+func (f TransactionFilter) Match(tx *model.Transaction) bool {
+	switch {
+	case !f.From.IsZero() && tx.CreatedAt.Before(f.From):
+		return false
+	case !f.To.IsZero() && !tx.CreatedAt.Before(f.To):
+		return false
+	case f.Status != "" && tx.Status != f.Status:
+		return false
+	case f.GatewayID != "" && tx.GatewayDetails.ID != f.GatewayID:
+		return false
+	}
+
+	return true
 }
 
 // memoryTransactionRepository represents an in-memory repository for transactions.
+// It stores copies of the transactions, so stored values are never mutated
+// and can be read after the lock is released.
 type memoryTransactionRepository struct {
 	mu           sync.RWMutex
 	transactions map[string]*model.Transaction
@@ -40,7 +76,8 @@ func (r *memoryTransactionRepository) Create(tx *model.Transaction) error {
 	}
 
 	tx.CreatedAt = time.Now()
-	r.transactions[tx.ID] = tx
+	stored := *tx
+	r.transactions[tx.ID] = &stored
 
 	return nil
 }
@@ -52,10 +89,12 @@ func (r *memoryTransactionRepository) GetByID(id string) (*model.Transaction, er
 
 	tx, exists := r.transactions[id]
 	if !exists {
-		return nil, fmt.Errorf("transaction %s not found", id)
+		return nil, fmt.Errorf("transaction %s: %w", id, ErrTransactionNotFound)
 	}
 
-	return tx, nil
+	found := *tx
+
+	return &found, nil
 }
 
 // GetByExternalID retrieves a transaction by its external ID.
@@ -65,11 +104,12 @@ func (r *memoryTransactionRepository) GetByExternalID(externalID string) (*model
 
 	for _, tx := range r.transactions {
 		if tx.ExternalID == externalID {
-			return tx, nil
+			found := *tx
+			return &found, nil
 		}
 	}
 
-	return nil, fmt.Errorf("transaction with external ID %s not found", externalID)
+	return nil, fmt.Errorf("transaction with external ID %s: %w", externalID, ErrTransactionNotFound)
 }
 
 // List returns all transactions.
@@ -79,23 +119,61 @@ func (r *memoryTransactionRepository) List() []*model.Transaction {
 
 	var txList []*model.Transaction
 	for _, tx := range r.transactions {
-		txList = append(txList, tx)
+		found := *tx
+		txList = append(txList, &found)
 	}
 
 	return txList
 }
 
+// Each calls fn for every transaction matching the filter, ordered by creation time,
+// stopping at the first error returned by fn. The lock is not held while fn runs.
+func (r *memoryTransactionRepository) Each(filter TransactionFilter, fn func(tx model.Transaction) error) error {
+	r.mu.RLock()
+
+	var matches []*model.Transaction
+	for _, tx := range r.transactions {
+		if filter.Match(tx) {
+			matches = append(matches, tx)
+		}
+	}
+
+	r.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].CreatedAt.Before(matches[j].CreatedAt)
+	})
+
+	for _, tx := range matches {
+		if err := fn(*tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Update updates the transaction.
 func (r *memoryTransactionRepository) Update(tx *model.Transaction) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, exists := r.transactions[tx.ID]; !exists {
-		return fmt.Errorf("transaction %s not found", tx.ID)
+		return fmt.Errorf("transaction %s: %w", tx.ID, ErrTransactionNotFound)
 	}
 
 	tx.UpdatedAt = time.Now()
-	r.transactions[tx.ID] = tx
+	stored := *tx
+	r.transactions[tx.ID] = &stored
 
 	return nil
 }
+
+// Ping reports whether the repository is reachable, which the in-memory repository always is
+// once its lock can be taken.
+func (r *memoryTransactionRepository) Ping(ctx context.Context) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return ctx.Err()
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	paymenthttp "go-payment-service/pkg/http"
+)
+
+// maxRequestIDLength bounds the request IDs accepted from callers
+const maxRequestIDLength = 128
+
+// RequestID accepts the X-Request-ID of the caller, or generates one, stores it in the request context and
+// returns it in the response. It is logged with every record of the request, see NewContextLogHandler, and
+// forwarded to the gateways.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(paymenthttp.HeaderRequestID)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+
+		w.Header().Set(paymenthttp.HeaderRequestID, id)
+
+		next.ServeHTTP(w, r.WithContext(paymenthttp.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID reports whether the request ID is set, not too long and made of printable ASCII characters only,
+// so it can't forge log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// contextLogHandler adds the request ID and trace ID of the context to the records
+type contextLogHandler struct {
+	slog.Handler
+}
+
+// NewContextLogHandler wraps the handler to add the request ID and trace ID of the context to the records
+// logged with it, e.g. with slog.InfoContext
+func NewContextLogHandler(h slog.Handler) slog.Handler {
+	return contextLogHandler{h}
+}
+
+// Handle implements slog.Handler
+func (h contextLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := paymenthttp.RequestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request-id", id))
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		r.AddAttrs(slog.String("trace-id", sc.TraceID().String()))
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h contextLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler
+func (h contextLogHandler) WithGroup(name string) slog.Handler {
+	return contextLogHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+)
+
+type TestRequestIDSuite struct {
+	suite.Suite
+}
+
+func (suite *TestRequestIDSuite) TestRequestID() {
+	testCases := []struct {
+		name      string
+		given     string
+		generated bool
+	}{
+		{
+			name:  "accepted",
+			given: "caller-42",
+		},
+		{
+			name:      "generated when missing",
+			generated: true,
+		},
+		{
+			name:      "generated when not printable",
+			given:     "forged\nlevel=ERROR",
+			generated: true,
+		},
+		{
+			name:      "generated when too long",
+			given:     strings.Repeat("a", maxRequestIDLength+1),
+			generated: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			h := newHandler(nil, nil, newHealthChecker())
+
+			req := httptest.NewRequest(http.MethodPost, "/deposit", strings.NewReader("{"))
+			req.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+			if tc.given != "" {
+				req.Header.Set(paymenthttp.HeaderRequestID, tc.given)
+			}
+
+			w := httptest.NewRecorder()
+			RequestID(h.mux).ServeHTTP(w, req)
+
+			id := w.Header().Get(paymenthttp.HeaderRequestID)
+			if tc.generated {
+				suite.NoError(uuid.Validate(id))
+			} else {
+				suite.Equal(tc.given, id)
+			}
+
+			suite.Equal(http.StatusBadRequest, w.Code)
+
+			var er model.ErrorResponse
+			suite.Require().NoError(json.NewDecoder(w.Body).Decode(&er))
+			suite.Equal(id, er.RequestID)
+		})
+	}
+}
+
+func (suite *TestRequestIDSuite) TestForwardedToGateway() {
+	forwarded := make(chan string, 1)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded <- r.Header.Get(paymenthttp.HeaderRequestID)
+	}))
+	defer gateway.Close()
+
+	req, err := http.NewRequestWithContext(paymenthttp.WithRequestID(context.Background(), "caller-42"), http.MethodPost, gateway.URL, nil)
+	suite.Require().NoError(err)
+
+	resp, err := paymenthttp.NewResilientHTTPClient().Do(req)
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	suite.Equal("caller-42", <-forwarded)
+}
+
+func (suite *TestRequestIDSuite) TestContextLogHandler() {
+	var buf bytes.Buffer
+	logger := slog.New(NewContextLogHandler(slog.NewTextHandler(&buf, nil))).With("component", "test")
+
+	logger.InfoContext(paymenthttp.WithRequestID(context.Background(), "caller-42"), "with request")
+	logger.InfoContext(context.Background(), "without request")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	suite.Require().Len(lines, 2)
+	suite.Contains(lines[0], "component=test")
+	suite.Contains(lines[0], "request-id=caller-42")
+	suite.NotContains(lines[1], "request-id")
+}
+
+func TestTestRequestIDSuite(t *testing.T) {
+	suite.Run(t, new(TestRequestIDSuite))
+}
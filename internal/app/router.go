@@ -0,0 +1,338 @@
+package app
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sony/gobreaker"
+
+	"go-payment-service/pkg/model"
+)
+
+var (
+	// ErrUnsupportedGateway is returned when the requested gateway is not registered
+	ErrUnsupportedGateway = errors.New("unsupported payment gateway")
+	// ErrInvalidWeights is returned when the weighted split of the traffic is invalid
+	ErrInvalidWeights = errors.New("invalid gateway weights")
+)
+
+// gatewayRouter sits in front of the registered gateways and chooses the ones a transaction is sent to
+type gatewayRouter struct {
+	// table holds the gateways, routing rules and weights, swapped as a whole when any of them changes
+	table atomic.Pointer[routingTable]
+	// mu serializes the updates of the table, so none of them is lost
+	mu sync.Mutex
+
+	// fingerprintSecret keys the card fingerprints of the weighted split, set once before serving
+	fingerprintSecret []byte
+}
+
+// routingTable is an immutable set of registered gateways and the rules choosing between them
+type routingTable struct {
+	gateways map[string]PaymentGateway
+	// rules select the preferred gateway of the transactions without a requested gateway, nil when there are none
+	rules *routingRules
+	// priority lists the gateway IDs from the most to the least preferred, gateways not listed come last
+	priority []string
+	// weights split the traffic not matching any rule between gateways, in percent; disabled when empty
+	weights []model.GatewayWeight
+	// callbackSecrets authenticate the status updates of the gateways, by gateway ID
+	callbackSecrets map[string]string
+	// contracts restrict the gateways of the merchants having some, by merchant then gateway ID
+	contracts map[string]map[string]merchantContract
+}
+
+// routingPlan is a routing decision along with the gateways it was made with, so a transaction
+// keeps its adapters when the gateways are reloaded while it is processed
+type routingPlan struct {
+	decision model.RoutingDecision
+	gateways map[string]PaymentGateway
+}
+
+// newGatewayRouter creates a new gateway router
+func newGatewayRouter(gateways map[string]PaymentGateway, rules *routingRules, priority ...string) *gatewayRouter {
+	r := &gatewayRouter{}
+	r.table.Store(&routingTable{gateways: gateways, rules: rules, priority: priority})
+
+	return r
+}
+
+// Reload atomically replaces the registered gateways, routing rules and priority, keeping the weights.
+// Transactions already routed keep the gateways they were routed with.
+func (r *gatewayRouter) Reload(gateways map[string]PaymentGateway, rules *routingRules, priority ...string) {
+	_ = r.update(func(t routingTable) (routingTable, error) {
+		t.gateways, t.rules, t.priority = gateways, rules, priority
+		return t, nil
+	})
+}
+
+// update atomically replaces the table with the one returned by apply from a copy of the current one,
+// unless apply fails. Updates are serialized, so the concurrent ones are applied one after the other.
+func (r *gatewayRouter) update(apply func(t routingTable) (routingTable, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, err := apply(*r.table.Load())
+	if err != nil {
+		return err
+	}
+
+	r.table.Store(&t)
+
+	return nil
+}
+
+// setCallbackSecrets replaces the callback secrets of the gateways, by gateway ID
+func (r *gatewayRouter) setCallbackSecrets(secrets map[string]string) {
+	_ = r.update(func(t routingTable) (routingTable, error) {
+		t.callbackSecrets = secrets
+		return t, nil
+	})
+}
+
+// setContracts replaces the contracts of the merchants with the gateways, by merchant then gateway ID
+func (r *gatewayRouter) setContracts(contracts map[string]map[string]merchantContract) {
+	_ = r.update(func(t routingTable) (routingTable, error) {
+		t.contracts = contracts
+		return t, nil
+	})
+}
+
+// validCallback reports whether the secret sent with a status update is the callback secret of the gateway
+func (r *gatewayRouter) validCallback(gatewayID, secret string) bool {
+	return validCallbackSecret(r.table.Load().callbackSecrets, gatewayID, secret)
+}
+
+// Gateway returns the registered gateway
+func (r *gatewayRouter) Gateway(id string) (PaymentGateway, bool) {
+	gateway, exists := r.table.Load().gateways[id]
+	return gateway, exists
+}
+
+// Route returns the gateways to try for the transaction, in order, and why they were chosen.
+// A requested gateway is the only candidate unless fallback is allowed, in which case the others follow it.
+// Without a requested gateway, the gateway selected by the first matching routing rule comes first or,
+// when no rule matches, the one the card is assigned to by the weighted split, if enabled.
+// The other candidates are ordered by health, then priority. Gateways not supporting the transaction are left out.
+func (r *gatewayRouter) Route(tx model.Transaction) (model.RoutingDecision, error) {
+	plan, err := r.plan(tx)
+	return plan.decision, err
+}
+
+// plan routes the transaction, see Route, and returns the decision with the gateways it was made with
+func (r *gatewayRouter) plan(tx model.Transaction) (routingPlan, error) {
+	t := r.forMerchant(tx.MerchantID)
+	details := tx.GatewayDetails
+	decision := model.RoutingDecision{
+		Attributes: t.rules.attributes(tx),
+	}
+
+	var preferred string
+
+	switch rule := t.rules.Match(decision.Attributes, t.gateways); {
+	case details.ID != "":
+		if _, exists := t.gateways[details.ID]; !exists {
+			return routingPlan{}, fmt.Errorf("%w: %s", ErrUnsupportedGateway, details.ID)
+		}
+
+		if !details.Fallback {
+			if err := checkCapabilities(details.ID, t.gateways[details.ID].Capabilities(), decision.Attributes); err != nil {
+				return routingPlan{}, err
+			}
+
+			decision.Gateways = []string{details.ID}
+			decision.Reason = "requested gateway"
+
+			return routingPlan{decision: decision, gateways: t.gateways}, nil
+		}
+
+		preferred = details.ID
+		decision.Reason = "requested gateway, then fallback by health and priority"
+	case rule != nil:
+		preferred = rule.Gateway
+		decision.Rule = rule.Name
+		decision.Reason = fmt.Sprintf("matched rule %q, then fallback by health and priority", rule.Name)
+	case r.weighted(tx.CardDetails, t, &preferred):
+		decision.Reason = fmt.Sprintf("weighted split to %s, sticky per card, then fallback by health and priority", preferred)
+	default:
+		decision.Reason = "no rule matched, by health and priority"
+	}
+
+	candidates := make([]string, 0, len(t.gateways))
+	for _, id := range t.ordered() {
+		if id != preferred {
+			candidates = append(candidates, id)
+		}
+	}
+
+	// gateways with an open circuit are tried last, as they are failing fast
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return t.healthy(candidates[i]) && !t.healthy(candidates[j])
+	})
+
+	if preferred != "" {
+		candidates = append([]string{preferred}, candidates...)
+	}
+
+	// leave out the gateways not supporting the transaction
+	var errs []error
+	for _, id := range candidates {
+		if err := checkCapabilities(id, t.gateways[id].Capabilities(), decision.Attributes); err != nil {
+			decision.Excluded = append(decision.Excluded, model.GatewayExclusion{GatewayID: id, Reason: err.Error()})
+			errs = append(errs, err)
+			continue
+		}
+
+		decision.Gateways = append(decision.Gateways, id)
+	}
+
+	if len(decision.Gateways) == 0 {
+		if len(errs) == 0 {
+			return routingPlan{}, fmt.Errorf("%w: no gateway available", ErrUnsupportedGateway)
+		}
+
+		return routingPlan{}, errors.Join(errs...)
+	}
+
+	return routingPlan{decision: decision, gateways: t.gateways}, nil
+}
+
+// Weights returns the weighted split of the traffic between gateways, empty when disabled
+func (r *gatewayRouter) Weights() []model.GatewayWeight {
+	return slices.Clone(r.table.Load().weights)
+}
+
+// SetWeights replaces the weighted split of the traffic, weights must add up to 100; no weights disable it
+func (r *gatewayRouter) SetWeights(weights []model.GatewayWeight) error {
+	return r.update(func(t routingTable) (routingTable, error) {
+		if err := validateWeights(weights, t.gateways); err != nil {
+			return t, err
+		}
+
+		t.weights = slices.Clone(weights)
+		return t, nil
+	})
+}
+
+// validateWeights checks the weights select registered gateways, once each, and add up to 100 unless empty
+func validateWeights(weights []model.GatewayWeight, gateways map[string]PaymentGateway) error {
+	total := 0
+	seen := make(map[string]bool, len(weights))
+
+	for _, w := range weights {
+		if _, exists := gateways[w.GatewayID]; !exists {
+			return fmt.Errorf("%w: %s", ErrUnsupportedGateway, w.GatewayID)
+		}
+
+		if seen[w.GatewayID] {
+			return fmt.Errorf("%w: duplicate gateway %s", ErrInvalidWeights, w.GatewayID)
+		}
+		seen[w.GatewayID] = true
+
+		if w.Weight < 0 {
+			return fmt.Errorf("%w: negative weight for %s", ErrInvalidWeights, w.GatewayID)
+		}
+
+		total += w.Weight
+	}
+
+	if len(weights) > 0 && total != 100 {
+		return fmt.Errorf("%w: weights add up to %d, expected 100", ErrInvalidWeights, total)
+	}
+
+	return nil
+}
+
+// weighted sets the gateway the card is assigned to by the weighted split and reports whether it is enabled.
+// A card always lands in the same bucket, so it keeps its gateway while the weights are unchanged
+// and only the cards of the shifted buckets move when they are adjusted. Cards assigned to a gateway
+// no longer registered are not split.
+func (r *gatewayRouter) weighted(card model.CardDetails, t *routingTable, id *string) bool {
+	if len(t.weights) == 0 {
+		return false
+	}
+
+	fingerprint, _ := hex.DecodeString(card.Fingerprint(r.fingerprintSecret)[:16])
+	bucket := int(binary.BigEndian.Uint64(fingerprint) % 100)
+
+	for _, w := range t.weights {
+		if bucket < w.Weight {
+			if _, exists := t.gateways[w.GatewayID]; !exists {
+				return false
+			}
+
+			*id = w.GatewayID
+			return true
+		}
+
+		bucket -= w.Weight
+	}
+
+	return false
+}
+
+// forMerchant returns the routing table of the merchant, see routingTable.forMerchant
+func (r *gatewayRouter) forMerchant(merchantID string) *routingTable {
+	t := r.table.Load()
+	return t.forMerchant(t.contracts[merchantID])
+}
+
+// forMerchant returns the table of the gateways the merchant has a contract with, sending its credentials and
+// accepting its amounts only. The table itself is returned when the merchant has no contracts.
+func (t *routingTable) forMerchant(contracts map[string]merchantContract) *routingTable {
+	if contracts == nil {
+		return t
+	}
+
+	gateways := make(map[string]PaymentGateway, len(contracts))
+	for id, contract := range contracts {
+		if gateway, exists := t.gateways[id]; exists {
+			gateways[id] = contract.apply(gateway)
+		}
+	}
+
+	return &routingTable{gateways: gateways, rules: t.rules, priority: t.priority, weights: t.weights}
+}
+
+// ordered returns the registered gateway IDs by priority, the gateways without priority being sorted by ID
+func (r *gatewayRouter) ordered() []string {
+	return r.table.Load().ordered()
+}
+
+// ordered returns the gateway IDs by priority, the gateways without priority being sorted by ID
+func (t *routingTable) ordered() []string {
+	ids := make([]string, 0, len(t.gateways))
+	seen := make(map[string]bool, len(t.gateways))
+
+	for _, id := range t.priority {
+		if _, exists := t.gateways[id]; exists && !seen[id] {
+			ids = append(ids, id)
+			seen[id] = true
+		}
+	}
+
+	rest := make([]string, 0, len(t.gateways))
+	for id := range t.gateways {
+		if !seen[id] {
+			rest = append(rest, id)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(ids, rest...)
+}
+
+// healthy reports whether the gateway circuit breaker is not open
+func (t *routingTable) healthy(id string) bool {
+	if reporter, ok := t.gateways[id].(breakerReporter); ok {
+		return reporter.BreakerState().State != gobreaker.StateOpen.String()
+	}
+
+	return true
+}
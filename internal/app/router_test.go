@@ -0,0 +1,488 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/suite"
+
+	"go-payment-service/pkg/model"
+)
+
+// stubGateway is a payment gateway answering with a fixed error, or succeeding
+type stubGateway struct {
+	err          error
+	breaker      gobreaker.State
+	capabilities model.GatewayCapabilities
+	calls        int
+	idleClosed   bool
+}
+
+func (g *stubGateway) ProcessTransaction(ctx context.Context, tx model.Transaction) (model.GatewayResponse, error) {
+	g.calls++
+
+	if g.err != nil {
+		return model.GatewayResponse{}, g.err
+	}
+
+	return model.GatewayResponse{TransactionID: "external-" + tx.ID, Status: model.Succeeded}, nil
+}
+
+func (g *stubGateway) Capabilities() model.GatewayCapabilities {
+	return g.capabilities
+}
+
+func (g *stubGateway) BreakerState() model.CircuitBreakerState {
+	return model.CircuitBreakerState{State: g.breaker.String()}
+}
+
+func (g *stubGateway) CloseIdleConnections() {
+	g.idleClosed = true
+}
+
+type TestRouterSuite struct {
+	suite.Suite
+}
+
+func (suite *TestRouterSuite) TestRoute() {
+	testCases := []struct {
+		name     string
+		given    model.GatewayDetails
+		open     string
+		expected []string
+		err      error
+	}{
+		{
+			name:     "requested gateway only",
+			given:    model.GatewayDetails{ID: "gatewayB"},
+			expected: []string{"gatewayB"},
+		},
+		{
+			name:     "requested gateway with fallback",
+			given:    model.GatewayDetails{ID: "gatewayB", Fallback: true},
+			expected: []string{"gatewayB", "gatewayA", "gatewayC"},
+		},
+		{
+			name:     "by priority",
+			expected: []string{"gatewayA", "gatewayB", "gatewayC"},
+		},
+		{
+			name:     "open circuit last",
+			open:     "gatewayA",
+			expected: []string{"gatewayB", "gatewayC", "gatewayA"},
+		},
+		{
+			name:  "unsupported gateway",
+			given: model.GatewayDetails{ID: "gatewayZ", Fallback: true},
+			err:   ErrUnsupportedGateway,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			gateways := map[string]PaymentGateway{
+				"gatewayA": &stubGateway{},
+				"gatewayB": &stubGateway{},
+				"gatewayC": &stubGateway{},
+			}
+			if tc.open != "" {
+				gateways[tc.open] = &stubGateway{breaker: gobreaker.StateOpen}
+			}
+
+			decision, err := newGatewayRouter(gateways, nil, "gatewayA", "gatewayB").Route(model.Transaction{GatewayDetails: tc.given})
+
+			suite.ErrorIs(err, tc.err)
+			suite.Equal(tc.expected, decision.Gateways)
+		})
+	}
+}
+
+func (suite *TestRouterSuite) TestRules() {
+	path := filepath.Join(suite.T().TempDir(), "rules.json")
+	suite.Require().NoError(os.WriteFile(path, []byte(`{
+		"rules": [
+			{"name": "amex", "gateway": "gatewayA", "cardBrands": ["amex"]},
+			{"name": "large eur withdrawals", "gateway": "gatewayA", "currencies": ["EUR"], "minAmount": 10000, "types": ["withdrawal"]},
+			{"name": "eur", "gateway": "gatewayB", "currencies": ["EUR"]},
+			{"name": "uk cards", "gateway": "gatewayB", "binCountries": ["GB"]}
+		],
+		"bins": {"4": "US", "4543": "GB"}
+	}`), 0o600))
+
+	gateways := map[string]PaymentGateway{
+		"gatewayA": &stubGateway{},
+		"gatewayB": &stubGateway{},
+	}
+
+	rules, err := loadRoutingRules(path)
+	suite.Require().NoError(err)
+	suite.Require().NoError(rules.validate([]string{"gatewayA", "gatewayB"}))
+
+	router := newGatewayRouter(gateways, rules, "gatewayA", "gatewayB")
+
+	testCases := []struct {
+		name            string
+		given           model.Transaction
+		expectedRule    string
+		expected        []string
+		expectedBrand   model.CardBrand
+		expectedCountry string
+	}{
+		{
+			name:            "card brand",
+			given:           model.Transaction{Type: model.Deposit, Amount: model.Money{Amount: 10, Currency: "EUR"}, CardDetails: model.CardDetails{Number: "378282246310005"}},
+			expectedRule:    "amex",
+			expected:        []string{"gatewayA", "gatewayB"},
+			expectedBrand:   model.Amex,
+			expectedCountry: "",
+		},
+		{
+			name:            "amount band and type",
+			given:           model.Transaction{Type: model.Withdrawal, Amount: model.Money{Amount: 15000, Currency: "EUR"}, CardDetails: model.CardDetails{Number: "4111111111111111"}},
+			expectedRule:    "large eur withdrawals",
+			expected:        []string{"gatewayA", "gatewayB"},
+			expectedBrand:   model.Visa,
+			expectedCountry: "US",
+		},
+		{
+			name:            "currency",
+			given:           model.Transaction{Type: model.Withdrawal, Amount: model.Money{Amount: 100, Currency: "EUR"}, CardDetails: model.CardDetails{Number: "4111111111111111"}},
+			expectedRule:    "eur",
+			expected:        []string{"gatewayB", "gatewayA"},
+			expectedBrand:   model.Visa,
+			expectedCountry: "US",
+		},
+		{
+			name:            "bin country",
+			given:           model.Transaction{Type: model.Deposit, Amount: model.Money{Amount: 100, Currency: "GBP"}, CardDetails: model.CardDetails{Number: "4543 0000 0000 0000"}},
+			expectedRule:    "uk cards",
+			expected:        []string{"gatewayB", "gatewayA"},
+			expectedBrand:   model.Visa,
+			expectedCountry: "GB",
+		},
+		{
+			name:            "no rule matched",
+			given:           model.Transaction{Type: model.Deposit, Amount: model.Money{Amount: 100, Currency: "USD"}, CardDetails: model.CardDetails{Number: "5555555555554444"}},
+			expected:        []string{"gatewayA", "gatewayB"},
+			expectedBrand:   model.Mastercard,
+			expectedCountry: "",
+		},
+		{
+			name:            "requested gateway ignores rules",
+			given:           model.Transaction{Type: model.Deposit, Amount: model.Money{Amount: 100, Currency: "EUR"}, GatewayDetails: model.GatewayDetails{ID: "gatewayA"}},
+			expected:        []string{"gatewayA"},
+			expectedBrand:   model.UnknownBrand,
+			expectedCountry: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			decision, err := router.Route(tc.given)
+			suite.Require().NoError(err)
+
+			suite.Equal(tc.expectedRule, decision.Rule)
+			suite.Equal(tc.expected, decision.Gateways)
+			suite.Equal(tc.expectedBrand, decision.Attributes.CardBrand)
+			suite.Equal(tc.expectedCountry, decision.Attributes.BINCountry)
+			suite.NotEmpty(decision.Reason)
+		})
+	}
+}
+
+func (suite *TestRouterSuite) TestRulesValidation() {
+	rules := routingRules{Rules: []routingRule{
+		{Name: "unknown gateway", Gateway: "gatewayZ"},
+		{Name: "empty band", Gateway: "gatewayA", MinAmount: 100, MaxAmount: 10},
+	}}
+
+	err := rules.validate([]string{"gatewayA"})
+
+	suite.ErrorIs(err, ErrUnsupportedGateway)
+	suite.ErrorContains(err, "maxAmount must be greater than minAmount")
+}
+
+func (suite *TestRouterSuite) TestCapabilities() {
+	gateways := map[string]PaymentGateway{
+		"gatewayA": &stubGateway{capabilities: model.GatewayCapabilities{
+			Currencies: []string{"USD", "EUR"},
+			Types:      []model.TransactionType{model.Deposit},
+			MinAmount:  1,
+			MaxAmount:  1000,
+			CardBrands: []model.CardBrand{model.Visa},
+		}},
+		"gatewayB": &stubGateway{capabilities: model.GatewayCapabilities{
+			Currencies: []string{"EUR"},
+		}},
+	}
+	router := newGatewayRouter(gateways, nil, "gatewayA", "gatewayB")
+
+	visa := model.CardDetails{Number: "4111111111111111"}
+
+	testCases := []struct {
+		name             string
+		given            model.Transaction
+		expected         []string
+		expectedExcluded []string
+		expectedErr      string
+	}{
+		{
+			name:     "supported by both",
+			given:    model.Transaction{Type: model.Deposit, Amount: model.Money{Amount: 10, Currency: "EUR"}, CardDetails: visa},
+			expected: []string{"gatewayA", "gatewayB"},
+		},
+		{
+			name:             "withdrawal to a deposit only gateway",
+			given:            model.Transaction{Type: model.Withdrawal, Amount: model.Money{Amount: 10, Currency: "EUR"}, CardDetails: visa},
+			expected:         []string{"gatewayB"},
+			expectedExcluded: []string{"gatewayA"},
+		},
+		{
+			name:             "amount above limit",
+			given:            model.Transaction{Type: model.Deposit, Amount: model.Money{Amount: 5000, Currency: "EUR"}, CardDetails: visa},
+			expected:         []string{"gatewayB"},
+			expectedExcluded: []string{"gatewayA"},
+		},
+		{
+			name:        "requested gateway not supporting the card brand",
+			given:       model.Transaction{Type: model.Deposit, Amount: model.Money{Amount: 10, Currency: "USD"}, CardDetails: model.CardDetails{Number: "378282246310005"}, GatewayDetails: model.GatewayDetails{ID: "gatewayA"}},
+			expectedErr: "gatewayA does not support amex cards",
+		},
+		{
+			name:        "no gateway supporting the currency",
+			given:       model.Transaction{Type: model.Deposit, Amount: model.Money{Amount: 10, Currency: "JPY"}, CardDetails: visa},
+			expectedErr: "gatewayB does not support currency JPY",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			decision, err := router.Route(tc.given)
+			if tc.expectedErr != "" {
+				suite.ErrorIs(err, ErrNotSupported)
+				suite.ErrorContains(err, tc.expectedErr)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Equal(tc.expected, decision.Gateways)
+
+			var excluded []string
+			for _, exclusion := range decision.Excluded {
+				excluded = append(excluded, exclusion.GatewayID)
+			}
+			suite.Equal(tc.expectedExcluded, excluded)
+		})
+	}
+}
+
+func (suite *TestRouterSuite) TestSetWeights() {
+	testCases := []struct {
+		name     string
+		given    []model.GatewayWeight
+		expected error
+	}{
+		{
+			name:  "percentages",
+			given: []model.GatewayWeight{{GatewayID: "gatewayA", Weight: 90}, {GatewayID: "gatewayB", Weight: 10}},
+		},
+		{
+			name: "disabled",
+		},
+		{
+			name:     "not adding up to 100",
+			given:    []model.GatewayWeight{{GatewayID: "gatewayA", Weight: 90}, {GatewayID: "gatewayB", Weight: 20}},
+			expected: ErrInvalidWeights,
+		},
+		{
+			name:     "duplicate gateway",
+			given:    []model.GatewayWeight{{GatewayID: "gatewayA", Weight: 50}, {GatewayID: "gatewayA", Weight: 50}},
+			expected: ErrInvalidWeights,
+		},
+		{
+			name:     "unsupported gateway",
+			given:    []model.GatewayWeight{{GatewayID: "gatewayZ", Weight: 100}},
+			expected: ErrUnsupportedGateway,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			router := newGatewayRouter(map[string]PaymentGateway{
+				"gatewayA": &stubGateway{},
+				"gatewayB": &stubGateway{},
+			}, nil)
+
+			err := router.SetWeights(tc.given)
+
+			suite.ErrorIs(err, tc.expected)
+			if tc.expected == nil {
+				suite.Equal(len(tc.given), len(router.Weights()))
+			}
+		})
+	}
+}
+
+func (suite *TestRouterSuite) TestWeightedSplit() {
+	router := newGatewayRouter(map[string]PaymentGateway{
+		"gatewayA": &stubGateway{},
+		"gatewayB": &stubGateway{},
+	}, nil, "gatewayA", "gatewayB")
+
+	route := func(number string) string {
+		decision, err := router.Route(model.Transaction{CardDetails: model.CardDetails{Number: number}})
+		suite.Require().NoError(err)
+
+		return decision.Gateways[0]
+	}
+
+	cards := make([]string, 1000)
+	for i := range cards {
+		cards[i] = fmt.Sprintf("4111%012d", i)
+	}
+
+	suite.Require().NoError(router.SetWeights([]model.GatewayWeight{{GatewayID: "gatewayA", Weight: 90}, {GatewayID: "gatewayB", Weight: 10}}))
+
+	before := make(map[string]string, len(cards))
+	counts := make(map[string]int)
+	for _, card := range cards {
+		before[card] = route(card)
+		counts[before[card]]++
+
+		// sticky per card
+		suite.Equal(before[card], route(card))
+	}
+
+	suite.InDelta(900, counts["gatewayA"], 50)
+	suite.InDelta(100, counts["gatewayB"], 50)
+
+	// shifting traffic to gatewayB only moves cards from gatewayA to gatewayB
+	suite.Require().NoError(router.SetWeights([]model.GatewayWeight{{GatewayID: "gatewayA", Weight: 80}, {GatewayID: "gatewayB", Weight: 20}}))
+
+	for _, card := range cards {
+		if before[card] == "gatewayB" {
+			suite.Equal("gatewayB", route(card))
+		}
+	}
+}
+
+func (suite *TestRouterSuite) TestReload() {
+	gatewayA, gatewayB := &stubGateway{}, &stubGateway{}
+	rules := &routingRules{Rules: []routingRule{{Name: "eur", Gateway: "gatewayB", Currencies: []string{"EUR"}}}}
+	router := newGatewayRouter(map[string]PaymentGateway{"gatewayA": gatewayA, "gatewayB": gatewayB}, rules, "gatewayA", "gatewayB")
+
+	tx := model.Transaction{Type: model.Deposit, Amount: model.Money{Amount: 10, Currency: "EUR"}}
+
+	plan, err := router.plan(tx)
+	suite.Require().NoError(err)
+	suite.Equal([]string{"gatewayB", "gatewayA"}, plan.decision.Gateways)
+
+	// gatewayB is disabled and gatewayA replaced while the transaction is processed
+	reloadedA := &stubGateway{}
+	router.Reload(map[string]PaymentGateway{"gatewayA": reloadedA}, rules, "gatewayA")
+
+	suite.Same(gatewayA, plan.gateways["gatewayA"], "routed transactions keep their adapters")
+	suite.Same(gatewayB, plan.gateways["gatewayB"])
+
+	decision, err := router.Route(tx)
+	suite.Require().NoError(err)
+	suite.Equal([]string{"gatewayA"}, decision.Gateways, "rules of disabled gateways are skipped")
+	suite.Empty(decision.Rule)
+
+	gateway, _ := router.Gateway("gatewayA")
+	suite.Same(reloadedA, gateway)
+}
+
+func (suite *TestRouterSuite) TestFailover() {
+	unreachable := fmt.Errorf("failed to send HTTP request: %w", gobreaker.ErrOpenState)
+	declined := errors.New("gateway returned non-200 status code: 500")
+
+	testCases := []struct {
+		name             string
+		givenErrA        error
+		expectedStatus   model.TransactionStatus
+		expectedGateway  string
+		expectedOutcomes []model.RoutingOutcome
+		expectedCallsB   int
+	}{
+		{
+			name:             "processed by first gateway",
+			expectedStatus:   model.Succeeded,
+			expectedGateway:  "gatewayA",
+			expectedOutcomes: []model.RoutingOutcome{model.RoutingProcessed},
+		},
+		{
+			name:             "fails over when unreachable",
+			givenErrA:        unreachable,
+			expectedStatus:   model.Succeeded,
+			expectedGateway:  "gatewayB",
+			expectedOutcomes: []model.RoutingOutcome{model.RoutingFailedOver, model.RoutingProcessed},
+			expectedCallsB:   1,
+		},
+		{
+			name:             "never fails over once the gateway may have processed it",
+			givenErrA:        declined,
+			expectedStatus:   model.Failed,
+			expectedGateway:  "gatewayA",
+			expectedOutcomes: []model.RoutingOutcome{model.RoutingFailed},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			gatewayB := &stubGateway{}
+			gateways := map[string]PaymentGateway{
+				"gatewayA": &stubGateway{err: tc.givenErrA},
+				"gatewayB": gatewayB,
+			}
+
+			repository := newMemoryTransactionRepository()
+			webhooks := newWebhookService(newMemoryWebhookRepository(), []byte("secret"))
+			service := newTransactionService(&sync.WaitGroup{}, newGatewayRouter(gateways, nil, "gatewayA", "gatewayB"), repository, newTransactionBroker(), webhooks)
+
+			res, err := service.Deposit(context.Background(), model.DepositRequest{})
+			if tc.expectedStatus == model.Failed {
+				suite.Error(err)
+			} else {
+				suite.Require().NoError(err)
+			}
+
+			var tx model.Transaction
+			suite.Require().NoError(repository.Each(TransactionFilter{}, func(t model.Transaction) error {
+				tx = t
+				return nil
+			}))
+
+			if err == nil {
+				suite.Equal(res.TransactionID, tx.ID)
+			}
+
+			suite.Equal(tc.expectedStatus, tx.Status)
+			suite.Equal(tc.expectedGateway, tx.GatewayDetails.ID)
+			suite.Equal(tc.expectedCallsB, gatewayB.calls)
+
+			outcomes := make([]model.RoutingOutcome, 0, len(tx.Routing))
+			for _, attempt := range tx.Routing {
+				outcomes = append(outcomes, attempt.Outcome)
+			}
+			suite.Equal(tc.expectedOutcomes, outcomes)
+
+			stats := service.stats.Get(tc.expectedGateway)
+			if tc.expectedStatus == model.Succeeded {
+				suite.Equal(model.GatewayStats{Succeeded: 1, SuccessRate: 1}, stats)
+			} else {
+				suite.Equal(model.GatewayStats{Failed: 1}, stats)
+			}
+		})
+	}
+}
+
+func TestTestRouterSuite(t *testing.T) {
+	suite.Run(t, new(TestRouterSuite))
+}
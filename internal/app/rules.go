@@ -0,0 +1,137 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"go-payment-service/pkg/model"
+)
+
+// routingRules selects the gateway of a transaction by its attributes, the first matching rule wins
+type routingRules struct {
+	Rules []routingRule `json:"rules"`
+	// BINs maps card number prefixes to ISO 3166 country codes, the longest matching prefix wins
+	BINs map[string]string `json:"bins"`
+}
+
+// routingRule selects a gateway for the transactions matching all of its conditions, empty conditions match everything
+type routingRule struct {
+	Name         string                  `json:"name"`
+	Gateway      string                  `json:"gateway"`
+	Currencies   []string                `json:"currencies,omitempty"`
+	MinAmount    float64                 `json:"minAmount,omitempty"` // Inclusive
+	MaxAmount    float64                 `json:"maxAmount,omitempty"` // Exclusive, no limit when 0
+	CardBrands   []model.CardBrand       `json:"cardBrands,omitempty"`
+	Types        []model.TransactionType `json:"types,omitempty"`
+	BINCountries []string                `json:"binCountries,omitempty"`
+}
+
+// loadRoutingRules reads the routing rules from a JSON file
+func loadRoutingRules(path string) (*routingRules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open routing rules: %w", err)
+	}
+	defer f.Close()
+
+	var rules routingRules
+
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to decode routing rules: %w", err)
+	}
+
+	return &rules, nil
+}
+
+// validate checks every rule has a name and selects one of the configured gateways, enabled or not
+func (r *routingRules) validate(gateways []string) error {
+	var errs []error
+
+	for i, rule := range r.Rules {
+		if rule.Name == "" {
+			errs = append(errs, fmt.Errorf("rule %d: name is required", i))
+		}
+
+		if !slices.Contains(gateways, rule.Gateway) {
+			errs = append(errs, fmt.Errorf("rule %d (%s): %w: %q", i, rule.Name, ErrUnsupportedGateway, rule.Gateway))
+		}
+
+		if rule.MaxAmount != 0 && rule.MaxAmount <= rule.MinAmount {
+			errs = append(errs, fmt.Errorf("rule %d (%s): maxAmount must be greater than minAmount", i, rule.Name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// attributes returns the attributes of the transaction the rules are evaluated against
+func (r *routingRules) attributes(tx model.Transaction) model.RoutingAttributes {
+	attrs := model.RoutingAttributes{
+		Type:      tx.Type,
+		Currency:  tx.Amount.Currency,
+		Amount:    tx.Amount.Amount,
+		CardBrand: model.DetectCardBrand(tx.CardDetails.Number),
+	}
+
+	if r != nil {
+		attrs.BINCountry = r.binCountry(tx.CardDetails.Number)
+	}
+
+	return attrs
+}
+
+// binCountry returns the country of the card issuer, from the longest BIN prefix of the card number
+func (r *routingRules) binCountry(number string) string {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(number)
+
+	country, longest := "", 0
+	for prefix, c := range r.BINs {
+		if len(prefix) > longest && strings.HasPrefix(digits, prefix) {
+			country, longest = c, len(prefix)
+		}
+	}
+
+	return country
+}
+
+// Match returns the first rule matching the attributes, if any, skipping the rules whose gateway is not registered
+// (e.g. disabled) so the transactions they match fall through to the next rules
+func (r *routingRules) Match(attrs model.RoutingAttributes, gateways map[string]PaymentGateway) *routingRule {
+	if r == nil {
+		return nil
+	}
+
+	for i := range r.Rules {
+		if _, exists := gateways[r.Rules[i].Gateway]; exists && r.Rules[i].matches(attrs) {
+			return &r.Rules[i]
+		}
+	}
+
+	return nil
+}
+
+func (rule routingRule) matches(attrs model.RoutingAttributes) bool {
+	switch {
+	case len(rule.Currencies) > 0 && !slices.ContainsFunc(rule.Currencies, func(c string) bool { return strings.EqualFold(c, attrs.Currency) }):
+		return false
+	case attrs.Amount < rule.MinAmount:
+		return false
+	case rule.MaxAmount != 0 && attrs.Amount >= rule.MaxAmount:
+		return false
+	case len(rule.CardBrands) > 0 && !slices.Contains(rule.CardBrands, attrs.CardBrand):
+		return false
+	case len(rule.Types) > 0 && !slices.Contains(rule.Types, attrs.Type):
+		return false
+	case len(rule.BINCountries) > 0 && !slices.ContainsFunc(rule.BINCountries, func(c string) bool { return strings.EqualFold(c, attrs.BINCountry) }):
+		return false
+	default:
+		return true
+	}
+}
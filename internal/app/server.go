@@ -1,85 +1,403 @@
 package app
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	paymenthttp "go-payment-service/pkg/http"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+
+	"go-payment-service/internal/config"
+	"go-payment-service/pkg/model"
 	"go-payment-service/test/emulator"
 )
 
 type Server interface {
-	Start(port string) error
+	Start() error
 	StartTest() *httptest.Server
+	// Reload applies the gateways and routing settings of the configuration without restart
+	Reload(cfg config.Config) error
 }
 
 type server struct {
-	handler *handler
-	wg      *sync.WaitGroup
+	cfg        config.Config
+	handler    *handler
+	grpcServer *grpc.Server
+	service    *transactionService
+	webhooks   *webhookService
+	broker     *transactionBroker
+	wg         *sync.WaitGroup
+
+	// reloadMu serializes the reloads of the gateways and routing settings below
+	reloadMu sync.Mutex
+	router   *gatewayRouter
+	gateways map[string]PaymentGateway
+	rules    *routingRules
+
+	// merchants authenticates the API calls, nil when the authentication is disabled
+	merchants *merchantAuthenticator
+
+	httpServer *http.Server
+	// draining is set once the server is shutting down, it is not ready to take traffic anymore
+	draining atomic.Bool
+
+	// emulator serves the gateways configured without endpoint, started on first use, guarded by reloadMu
+	emulator *httptest.Server
+	// emulatorSecret authenticates the callbacks of the emulator
+	emulatorSecret string
+
+	// tracerProvider exports the spans, nil when tracing is disabled
+	tracerProvider *sdktrace.TracerProvider
+
+	// err is the initialization error returned by Start
+	err error
 }
 
-func NewServer() Server {
+// NewServer creates the server from a validated configuration, see config.Load
+func NewServer(cfg config.Config) Server {
 	s := &server{
-		wg: &sync.WaitGroup{},
+		cfg:            cfg,
+		broker:         newTransactionBroker(),
+		wg:             &sync.WaitGroup{},
+		emulatorSecret: randomSecret(),
 	}
 
-	// Initialize HTTP client
-	resilientHTTPClient := paymenthttp.NewResilientHTTPClient()
+	tracerProvider, err := setupTracing(cfg.Tracing)
+	if err != nil {
+		s.err = err
+	}
+	s.tracerProvider = tracerProvider
+
+	// Initialize payment gateways from their configuration
+	gateways, err := newGateways(cfg.Gateways, s.emulatorURL)
+	if err != nil {
+		s.err = errors.Join(s.err, err)
+	}
 
-	// Initialize payment gateways
-	gatewayEmulator := emulator.Start()
+	// Load the routing rules, if any
+	rules, err := loadRoutingRulesFile(cfg.Routing.RulesFile, gatewayIDs(cfg.Gateways))
+	if err != nil {
+		s.err = errors.Join(s.err, err)
+	}
 
-	gateways := map[string]PaymentGateway{
-		"gatewayA": newGatewayAAdapter(resilientHTTPClient, gatewayEmulator.URL),
-		"gatewayB": newGatewayBAdapter(resilientHTTPClient, gatewayEmulator.URL),
+	s.gateways, s.rules = gateways, rules
+	s.router = newGatewayRouter(gateways, rules, cfg.Routing.Priority...)
+	s.router.setCallbackSecrets(callbackSecrets(cfg.Gateways, s.emulatorSecret))
+	contracts := newMerchantContracts(cfg.Merchants)
+	if err := validateContracts(contracts, gateways); err != nil {
+		s.err = errors.Join(s.err, fmt.Errorf("invalid merchant gateways: %w", err))
+	}
+	s.router.setContracts(contracts)
+	s.router.fingerprintSecret = secretOrRandom(cfg.Routing.FingerprintSecret, "card fingerprint")
+	if err := s.router.SetWeights(gatewayWeights(cfg.Routing.Weights)); err != nil {
+		s.err = errors.Join(s.err, fmt.Errorf("invalid routing weights: %w", err))
 	}
 
+	// Initialize merchant webhooks, signed with the secret of their merchant
+	var webhookSecret []byte
+	if cfg.Auth.Disabled {
+		webhookSecret = secretOrRandom(cfg.Webhooks.Secret, "webhook")
+	}
+	webhookRepository, err := newWebhookRepository(cfg.Webhooks.QueueFile)
+	if err != nil {
+		s.err = errors.Join(s.err, err)
+		webhookRepository = newMemoryWebhookRepository()
+	}
+	s.webhooks = newWebhookService(webhookRepository, webhookSecret)
+	s.webhooks.secrets = webhookSecrets(cfg.Merchants)
+	s.webhooks.allowPrivateURLs = cfg.Webhooks.AllowPrivateURLs
+
 	memoryRepository := newMemoryTransactionRepository()
-	transactionService := newTransactionService(s.wg, gateways, memoryRepository)
-	s.handler = newHandler(transactionService)
+	s.service = newTransactionService(s.wg, s.router, memoryRepository, s.broker, s.webhooks)
+	s.service.callbackURL = cfg.CallbackURL
+	s.service.processTimeout = time.Duration(cfg.Processing.Timeout)
+
+	// Readiness checks
+	health := newHealthChecker()
+	health.Register("repository", repositoryHealthCheck(memoryRepository))
+	health.Register("gateways", gatewaysHealthCheck(s.router))
+	health.Register("shutdown", drainingHealthCheck(&s.draining))
+
+	if cfg.Auth.Disabled {
+		slog.Warn("server: authentication disabled, the API is open")
+	} else {
+		s.merchants = newMerchantAuthenticator(cfg.Merchants)
+	}
+
+	s.handler = newHandler(s.service, s.webhooks, health)
+	s.handler.mux.Handle("GET /metrics", s.service.metrics.Handler())
+	s.grpcServer = newGRPCServer(s.service, s.merchants)
 
 	return s
 }
 
-func (s *server) Start(port string) error {
+// Start serves HTTP and gRPC until the process receives SIGINT or SIGTERM, or a server fails, then shuts down
+// gracefully. It returns the error of the server that failed or of the shutdown, if any.
+func (s *server) Start() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	port, grpcPort := s.cfg.HTTP.Port, s.cfg.GRPC.Port
+
+	httpLis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on HTTP port: %w", err)
+	}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", grpcPort))
+	if err != nil {
+		httpLis.Close()
+		return fmt.Errorf("failed to listen on gRPC port: %w", err)
+	}
+
 	// Start HTTP server
-	slog.Info("server: listening on", slog.String("port", port))
+	slog.Info("server: listening on", slog.String("port", port), slog.String("grpc-port", grpcPort))
+
+	// Gateways notify this service, on localhost when no public URL is configured
+	if s.service.callbackURL == "" {
+		s.service.callbackURL = fmt.Sprintf("http://localhost:%s/callback", port)
+	}
+
+	s.webhooks.Start()
 
 	// Setup signal catching
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
 
-	server := &http.Server{
-		Addr:              fmt.Sprintf(":%s", port),
-		Handler:           Logging(s.handler.mux),
-		ReadHeaderTimeout: 10 * time.Second,
+	s.httpServer = &http.Server{
+		Handler: Chain(
+			s.handler.mux,
+			RequestID,
+			Logging,
+			s.service.metrics.instrument,
+			Recover,
+			SecurityHeaders,
+			authenticate(s.handler.mux, s.merchants, publicRoutes...),
+			LimitBody(s.handler.mux, s.cfg.HTTP.MaxBodyBytes, s.cfg.HTTP.RouteMaxBodyBytes),
+		),
+		ReadHeaderTimeout: time.Duration(s.cfg.HTTP.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(s.cfg.HTTP.ReadTimeout),
+		WriteTimeout:      time.Duration(s.cfg.HTTP.WriteTimeout),
+		IdleTimeout:       time.Duration(s.cfg.HTTP.IdleTimeout),
 	}
 
+	// Both servers report why they stopped serving, nil once shut down
+	serveErr := make(chan error, 2)
+
 	// Run server in a goroutine
-	var err error
 	go func() {
-		if err = server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.Error("server: ListenAndServe() error", slog.Any("error", err))
+		if err := s.httpServer.Serve(httpLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("HTTP server failed: %w", err)
 		}
 	}()
 
-	<-quit
+	// Run gRPC server in a goroutine
+	go func() {
+		if err := s.grpcServer.Serve(lis); err != nil {
+			serveErr <- fmt.Errorf("gRPC server failed: %w", err)
+		}
+	}()
+
+	var cause error
+
+	select {
+	case sig := <-quit:
+		slog.Info("server: signal received, shutting down", slog.String("signal", sig.String()))
+	case cause = <-serveErr:
+		slog.Error("server: shutting down", slog.Any("error", cause))
+	}
+
+	return errors.Join(cause, s.shutdown())
+}
+
+// shutdown stops the service in order within the shutdown timeout: it reports not ready, stops accepting
+// requests and drains the in-flight ones, waits for the transactions being sent to the gateways, then flushes
+// the due webhooks. It returns an error when something could not be drained in time.
+func (s *server) shutdown() error {
+	s.draining.Store(true)
+	slog.Info("server: marked not ready", slog.Duration("delay", time.Duration(s.cfg.Shutdown.Delay)))
+
+	// Keep serving while load balancers notice the service is not ready
+	time.Sleep(time.Duration(s.cfg.Shutdown.Delay))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.cfg.Shutdown.Timeout))
+	defer cancel()
+
+	var errs []error
+
+	// Release the clients watching transactions, so their requests and streams complete
+	s.broker.Close()
+
+	slog.Info("server: draining HTTP requests...")
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to drain HTTP requests: %w", err))
+	}
+
+	slog.Info("server: stopping gRPC server...")
+	s.stopGRPC(ctx)
 
 	slog.Info("server: waiting for all transactions to complete...")
-	s.wg.Wait()
+	if !waitContext(ctx, s.wg) {
+		errs = append(errs, fmt.Errorf("failed to wait for the transactions being processed: %w", ctx.Err()))
+	}
+
+	slog.Info("server: flushing webhook deliveries...")
+	if err := s.webhooks.Stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to wait for the webhook deliveries in progress: %w", err))
+	}
+	if pending := s.webhooks.Flush(ctx); pending > 0 {
+		slog.Warn("server: webhook deliveries still pending", slog.Int("pending", pending))
+	}
+	if journal, ok := s.webhooks.repository.(io.Closer); ok {
+		if err := journal.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close the webhook queue: %w", err))
+		}
+	}
+
+	// no reload can start the emulator anymore once it is closed, as the server is draining
+	s.reloadMu.Lock()
+	if s.emulator != nil {
+		s.emulator.Close()
+	}
+	s.reloadMu.Unlock()
+
+	if s.tracerProvider != nil {
+		slog.Info("server: flushing traces...")
+		if err := s.tracerProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush traces: %w", err))
+		}
+	}
 
-	slog.Info("server: shutting down...")
+	slog.Info("server: shut down")
+
+	return errors.Join(errs...)
+}
+
+// waitContext waits for the wait group until ctx is done and reports whether it completed
+func waitContext(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stopGRPC stops the gRPC server gracefully, cancelling the RPCs that are still running
+// (e.g. watch streams) once the gRPC shutdown timeout elapses or ctx is done
+func (s *server) stopGRPC(ctx context.Context) {
+	stopped := make(chan struct{})
+
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+	case <-time.After(time.Duration(s.cfg.GRPC.ShutdownTimeout)):
+		s.grpcServer.Stop()
+	}
+}
+
+// emulatorURL starts the in-process gateway emulator, if not started yet, and returns its URL.
+// It is called while creating the server or with reloadMu held.
+func (s *server) emulatorURL() string {
+	if s.emulator == nil {
+		slog.Info("server: starting the gateway emulator")
+		s.emulator = emulator.StartWithCallbackSecret(s.emulatorSecret)
+	}
 
-	return err
+	return s.emulator.URL
 }
 
 func (s *server) StartTest() *httptest.Server {
-	return httptest.NewServer(s.handler.mux)
+	ts := httptest.NewUnstartedServer(s.handler.mux)
+
+	// Gateways notify the test server
+	s.service.callbackURL = fmt.Sprintf("http://%s/callback", ts.Listener.Addr())
+
+	ts.Start()
+	s.webhooks.Start()
+
+	return ts
+}
+
+// secretOrRandom returns the configured secret, falling back to a random one (which no one can know,
+// but which changes on restart and differs between instances) when none is configured
+func secretOrRandom(secret, name string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+
+	slog.Warn("server: no " + name + " secret configured, using a random secret")
+
+	random := make([]byte, 32)
+	_, _ = rand.Read(random)
+
+	return random
+}
+
+// randomSecret returns a random hex-encoded secret, e.g. shared with the emulator
+func randomSecret() string {
+	random := make([]byte, 32)
+	_, _ = rand.Read(random)
+
+	return hex.EncodeToString(random)
+}
+
+// loadRoutingRulesFile loads the routing rules from the JSON file, transactions are routed by priority only
+// when there is none
+func loadRoutingRulesFile(path string, gateways []string) (*routingRules, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	rules, err := loadRoutingRules(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rules.validate(gateways); err != nil {
+		return nil, fmt.Errorf("invalid routing rules: %w", err)
+	}
+
+	slog.Info("server: routing rules loaded", slog.String("path", path), slog.Int("rules", len(rules.Rules)))
+
+	return rules, nil
+}
+
+// gatewayWeights converts the configured weighted split of the traffic
+func gatewayWeights(weights []config.GatewayWeight) []model.GatewayWeight {
+	converted := make([]model.GatewayWeight, 0, len(weights))
+	for _, w := range weights {
+		converted = append(converted, model.GatewayWeight{GatewayID: w.GatewayID, Weight: w.Weight})
+	}
+
+	return converted
 }
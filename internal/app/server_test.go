@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"go-payment-service/internal/config"
+	"go-payment-service/pkg/model"
+)
+
+type TestServerSuite struct {
+	suite.Suite
+}
+
+// serve starts the server with the handler on a random port and returns its URL
+func (suite *TestServerSuite) serve(timeout time.Duration, handler http.Handler) (*server, string) {
+	cfg := config.Default()
+	cfg.Shutdown.Timeout = config.Duration(timeout)
+
+	s := NewServer(cfg).(*server)
+	suite.Require().NoError(s.err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+
+	s.httpServer = &http.Server{Handler: handler, ReadHeaderTimeout: time.Second}
+	go func() { _ = s.httpServer.Serve(lis) }()
+
+	s.webhooks.Start()
+
+	return s, "http://" + lis.Addr().String()
+}
+
+func (suite *TestServerSuite) TestShutdownDrainsRequests() {
+	started := make(chan struct{})
+	s, url := suite.serve(5*time.Second, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	result := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		result <- err
+	}()
+
+	<-started
+	suite.Require().NoError(s.shutdown())
+
+	suite.True(s.draining.Load())
+	suite.NoError(<-result, "the in-flight request completes")
+
+	_, err := http.Get(url)
+	suite.Error(err, "new requests are refused")
+}
+
+func (suite *TestServerSuite) TestShutdownDeadline() {
+	s, _ := suite.serve(50*time.Millisecond, http.NotFoundHandler())
+
+	// a transaction still being processed
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	suite.ErrorContains(s.shutdown(), "failed to wait for the transactions being processed: context deadline exceeded")
+}
+
+func (suite *TestServerSuite) TestShutdownHangingWebhook() {
+	received := make(chan struct{})
+	release := make(chan struct{})
+	merchant := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		<-release
+	}))
+	defer merchant.Close()
+	defer close(release)
+
+	s, _ := suite.serve(100*time.Millisecond, http.NotFoundHandler())
+	s.webhooks.secret = []byte("secret")
+	s.webhooks.allowPrivateURLs = true
+
+	tx := model.Transaction{ID: "tx", Status: model.Succeeded, GatewayDetails: model.GatewayDetails{CallbackURL: merchant.URL}}
+	suite.Require().NoError(s.webhooks.Enqueue(context.Background(), tx))
+	<-received
+
+	start := time.Now()
+	suite.ErrorContains(s.shutdown(), "failed to wait for the webhook deliveries in progress: context deadline exceeded")
+	suite.Less(time.Since(start), time.Second, "the shutdown timeout bounds the deliveries in progress")
+
+	pending := s.webhooks.repository.ListByStatus(model.DeliveryPending)
+	suite.Require().Len(pending, 1, "the aborted delivery stays queued")
+	suite.Zero(pending[0].Attempts)
+}
+
+func TestTestServerSuite(t *testing.T) {
+	suite.Run(t, new(TestServerSuite))
+}
@@ -2,43 +2,70 @@ package app
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
+	paymenthttp "go-payment-service/pkg/http"
 	"go-payment-service/pkg/model"
 )
 
+// defaultProcessTimeout is the deadline for the gateways to accept a transaction when none is configured
+const defaultProcessTimeout = 30 * time.Second
+
 type TransactionService interface {
 	Deposit(ctx context.Context, req model.DepositRequest) (model.DepositResponse, error)
 	Withdrawal(ctx context.Context, req model.WithdrawalRequest) (model.WithdrawalResponse, error)
 	UpdateStatus(ctx context.Context, req model.TransactionStatusUpdate) error
 	GetByID(ctx context.Context, id string) (*model.Transaction, error)
+	Export(ctx context.Context, filter TransactionFilter, fn func(tx model.Transaction) error) error
+	Watch(ctx context.Context, id string) (<-chan model.Transaction, error)
+	Gateways(ctx context.Context) []model.GatewayStatus
+	DryRunRoute(ctx context.Context, req model.RoutingDryRunRequest) (model.RoutingDecision, error)
+	Weights(ctx context.Context) model.RoutingWeights
+	SetWeights(ctx context.Context, weights model.RoutingWeights) error
 }
 
 type transactionService struct {
-	gateways   map[string]PaymentGateway
+	router     *gatewayRouter
+	stats      *gatewayStats
+	metrics    *metrics
 	repository TransactionRepository
+	broker     *transactionBroker
+	webhooks   WebhookService
 	wg         *sync.WaitGroup
+
+	// callbackURL is the URL of this service's callback endpoint, registered with the gateways
+	callbackURL string
+	// processTimeout is the deadline for the gateways to accept a transaction, failovers included
+	processTimeout time.Duration
 }
 
 // newTransactionService creates a new transaction service
-func newTransactionService(wg *sync.WaitGroup, gateways map[string]PaymentGateway, repo TransactionRepository) TransactionService {
+func newTransactionService(wg *sync.WaitGroup, router *gatewayRouter, repo TransactionRepository, broker *transactionBroker, webhooks WebhookService) *transactionService {
 	return &transactionService{
-		gateways:   gateways,
+		router:     router,
+		stats:      newGatewayStats(),
+		metrics:    newMetrics(router),
 		repository: repo,
+		broker:     broker,
+		webhooks:   webhooks,
 		wg:         wg,
+
+		processTimeout: defaultProcessTimeout,
 	}
 }
 
 func (s *transactionService) Deposit(ctx context.Context, req model.DepositRequest) (model.DepositResponse, error) {
-	tx, err := s.create(req.BaseRequest, model.Deposit)
+	tx, err := s.create(ctx, req.BaseRequest, model.Deposit)
 	if err != nil {
-		slog.Debug("deposit: failed to create transaction", slog.Any("error", err))
+		slog.DebugContext(ctx, "deposit: failed to create transaction", slog.Any("error", err))
 		return model.DepositResponse{}, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
@@ -46,7 +73,7 @@ func (s *transactionService) Deposit(ctx context.Context, req model.DepositReque
 	errChain := s.process(ctx, tx)
 	for err := range errChain {
 		if err != nil {
-			slog.Debug("deposit: failed to process transaction", slog.Any("error", err))
+			slog.DebugContext(ctx, "deposit: failed to process transaction", slog.Any("error", err))
 			return model.DepositResponse{}, err
 		}
 	}
@@ -61,9 +88,9 @@ func (s *transactionService) Deposit(ctx context.Context, req model.DepositReque
 }
 
 func (s *transactionService) Withdrawal(ctx context.Context, req model.WithdrawalRequest) (model.WithdrawalResponse, error) {
-	tx, err := s.create(req.BaseRequest, model.Withdrawal)
+	tx, err := s.create(ctx, req.BaseRequest, model.Withdrawal)
 	if err != nil {
-		slog.Debug("withdrawal: failed to create transaction", slog.Any("error", err))
+		slog.DebugContext(ctx, "withdrawal: failed to create transaction", slog.Any("error", err))
 		return model.WithdrawalResponse{}, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
@@ -71,7 +98,7 @@ func (s *transactionService) Withdrawal(ctx context.Context, req model.Withdrawa
 	errChain := s.process(ctx, tx)
 	for err := range errChain {
 		if err != nil {
-			slog.Debug("withdrawal: failed to process transaction", slog.Any("error", err))
+			slog.DebugContext(ctx, "withdrawal: failed to process transaction", slog.Any("error", err))
 			return model.WithdrawalResponse{}, err
 		}
 	}
@@ -88,33 +115,192 @@ func (s *transactionService) Withdrawal(ctx context.Context, req model.Withdrawa
 func (s *transactionService) UpdateStatus(ctx context.Context, req model.TransactionStatusUpdate) error {
 	tx, err := s.repository.GetByExternalID(req.TransactionID)
 	if err != nil {
-		slog.Debug("update status: could not find transaction", slog.Any("error", err))
+		s.metrics.callbacks.WithLabelValues("not_found").Inc()
+		slog.DebugContext(ctx, "update status: could not find transaction", slog.Any("error", err))
 		return fmt.Errorf("could not find transaction. err: %w", err)
 	}
 
+	if !s.router.validCallback(tx.GatewayDetails.ID, callbackSecretFromContext(ctx)) {
+		s.metrics.callbacks.WithLabelValues("unauthenticated").Inc()
+		return fmt.Errorf("transaction %s: %w", tx.ID, ErrCallbackUnauthenticated)
+	}
+
+	previous := tx.Status
 	tx.Status = req.Status
 	tx.UpdatedAt = time.Now()
 
 	if err := s.repository.Update(tx); err != nil {
-		slog.Debug("update status: could not update transaction", slog.Any("error", err))
+		s.metrics.callbacks.WithLabelValues("failed").Inc()
+		slog.DebugContext(ctx, "update status: could not update transaction", slog.Any("error", err))
 		return fmt.Errorf("could not update transaction. err: %w", err)
 	}
 
+	if tx.Status == previous {
+		s.metrics.callbacks.WithLabelValues("unchanged").Inc()
+		return nil
+	}
+
+	s.metrics.callbacks.WithLabelValues("updated").Inc()
+	s.stats.Record(previous, *tx)
+	s.publish(ctx, *tx)
+
 	return nil
 }
 
+// GetByID returns the transaction, not found when it belongs to another merchant than the one of ctx
 func (s *transactionService) GetByID(ctx context.Context, id string) (*model.Transaction, error) {
-	return s.repository.GetByID(id)
+	tx, err := s.repository.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ownedBy(ctx, tx) {
+		return nil, fmt.Errorf("transaction %s: %w", id, ErrTransactionNotFound)
+	}
+
+	return tx, nil
+}
+
+// Export calls fn for every transaction matching the filter, with the card details masked
+func (s *transactionService) Export(ctx context.Context, filter TransactionFilter, fn func(tx model.Transaction) error) error {
+	return s.repository.Each(filter, func(tx model.Transaction) error {
+		// stop exporting when the client goes away
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !ownedBy(ctx, &tx) {
+			return nil
+		}
+
+		return fn(tx.Masked())
+	})
+}
+
+// Watch returns a channel receiving the current state of the transaction and then the transaction
+// every time its status changes. The channel is closed once a terminal status is reached,
+// the context is done or the service shuts down; callers must cancel the context when they stop reading.
+func (s *transactionService) Watch(ctx context.Context, id string) (<-chan model.Transaction, error) {
+	// subscribe before reading the current state, so no change is missed
+	updates, unsubscribe := s.broker.Subscribe(id)
+
+	tx, err := s.GetByID(ctx, id)
+	if err != nil {
+		unsubscribe()
+		return nil, err
+	}
+
+	out := make(chan model.Transaction)
+
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		send := func(tx model.Transaction) bool {
+			select {
+			case out <- tx:
+				return !tx.Status.IsTerminal()
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if !send(*tx) {
+			return
+		}
+
+		last := tx.Status
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+
+				if update.Status == last {
+					continue
+				}
+
+				last = update.Status
+				if !send(update) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Gateways returns the gateways available to the merchant of ctx with their capabilities, narrowed to its
+// limits, the state of their circuit breakers and their success rate, by routing priority
+func (s *transactionService) Gateways(ctx context.Context) []model.GatewayStatus {
+	t := s.router.forMerchant(merchantFromContext(ctx).ID)
+	ids := t.ordered()
+
+	statuses := make([]model.GatewayStatus, 0, len(ids))
+	for _, id := range ids {
+		gateway := t.gateways[id]
+
+		status := model.GatewayStatus{
+			ID:             id,
+			CircuitBreaker: model.CircuitBreakerState{State: "unknown"},
+			Stats:          s.stats.Get(id),
+			Capabilities:   gateway.Capabilities(),
+		}
+		if r, ok := gateway.(breakerReporter); ok {
+			status.CircuitBreaker = r.BreakerState()
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// DryRunRoute explains how a transaction like the sample would be routed, without processing it
+func (s *transactionService) DryRunRoute(ctx context.Context, req model.RoutingDryRunRequest) (model.RoutingDecision, error) {
+	return s.router.Route(model.Transaction{
+		MerchantID:     merchantFromContext(ctx).ID,
+		Amount:         req.Amount,
+		CardDetails:    model.CardDetails{Number: req.CardNumber},
+		Type:           req.Type,
+		GatewayDetails: req.GatewayDetails,
+	})
+}
+
+// Weights returns the weighted split of the traffic between gateways
+func (s *transactionService) Weights(ctx context.Context) model.RoutingWeights {
+	return model.RoutingWeights{Weights: s.router.Weights()}
+}
+
+// SetWeights adjusts the weighted split of the traffic between gateways
+func (s *transactionService) SetWeights(ctx context.Context, weights model.RoutingWeights) error {
+	if err := s.router.SetWeights(weights.Weights); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "routing: gateway weights updated", slog.Any("weights", weights.Weights))
+
+	return nil
 }
 
-func (s *transactionService) create(req model.BaseRequest, transactionType model.TransactionType) (model.Transaction, error) {
-	if _, exists := s.gateways[req.GatewayDetails.ID]; !exists {
-		slog.Debug("create: unsupported payment gateway", slog.String("gateway", req.GatewayDetails.ID))
-		return model.Transaction{}, errors.New("unsupported payment gateway")
+// publish notifies the transaction status change to its watchers and to the merchant
+func (s *transactionService) publish(ctx context.Context, tx model.Transaction) {
+	s.metrics.ObserveTransaction(tx)
+	s.broker.Publish(tx)
+
+	if err := s.webhooks.Enqueue(ctx, tx); err != nil {
+		slog.ErrorContext(ctx, "publish: could not enqueue webhook", slog.String("transaction-id", tx.ID), slog.Any("error", err))
 	}
+}
 
+func (s *transactionService) create(ctx context.Context, req model.BaseRequest, transactionType model.TransactionType) (model.Transaction, error) {
 	tx := model.Transaction{
 		ID:             uuid.New().String(),
+		MerchantID:     merchantFromContext(ctx).ID,
 		Amount:         req.Amount,
 		CardDetails:    req.CardDetails,
 		Type:           transactionType,
@@ -122,67 +308,178 @@ func (s *transactionService) create(req model.BaseRequest, transactionType model
 		GatewayDetails: req.GatewayDetails,
 	}
 
+	if err := s.webhooks.CheckURL(req.GatewayDetails.CallbackURL); err != nil {
+		slog.DebugContext(ctx, "create: invalid callback URL", slog.Any("error", err))
+		return model.Transaction{}, err
+	}
+
+	// pre-flight validation, no transaction is created when no gateway can process it
+	if _, err := s.router.Route(tx); err != nil {
+		slog.DebugContext(ctx, "create: no payment gateway can process the transaction", slog.String("gateway", req.GatewayDetails.ID), slog.Any("error", err))
+		return model.Transaction{}, err
+	}
+
 	if err := s.repository.Create(&tx); err != nil {
-		slog.Debug("create: could not create transaction", slog.Any("error", err))
+		slog.DebugContext(ctx, "create: could not create transaction", slog.Any("error", err))
 		return model.Transaction{}, fmt.Errorf("could not create transaction: %w", err)
 	}
 
+	s.metrics.ObserveTransaction(tx)
+
 	return tx, nil
 }
 
 func (s *transactionService) process(ctx context.Context, tx model.Transaction) <-chan error {
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 
-	gateway, exists := s.gateways[tx.GatewayDetails.ID]
-	if !exists {
-		slog.Debug("process: payment gateway not registered", slog.String("gateway", tx.GatewayDetails.ID))
-		errChan <- errors.New("payment gateway not registered")
+	plan, err := s.router.plan(tx)
+	if err != nil {
+		slog.DebugContext(ctx, "process: payment gateway not registered", slog.String("gateway", tx.GatewayDetails.ID))
+		errChan <- err
 		close(errChan)
 
 		return errChan
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, s.processTimeout)
 	s.wg.Add(1)
+	s.metrics.inFlight.Inc()
 
 	go func() {
 		defer close(errChan)
 		defer cancel()
 		defer s.wg.Done()
+		defer s.metrics.inFlight.Dec()
+
+		ctx, span := tracer.Start(ctx, "transactionService.process", trace.WithAttributes(
+			attribute.String("transaction.id", tx.ID),
+			attribute.String("transaction.type", string(tx.Type)),
+		))
+		defer func() {
+			span.SetAttributes(attribute.String("transaction.status", string(tx.Status)))
+			span.End()
+		}()
 
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			res, err := gateway.ProcessTransaction(tx)
+			res, err := s.route(ctx, &tx, plan)
 			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+
+				previous := tx.Status
 				tx.Status = model.Failed
 
 				if err := s.repository.Update(&tx); err != nil {
-					slog.Debug("process: could not update transaction", slog.Any("error", err))
+					slog.DebugContext(ctx, "process: could not update transaction", slog.Any("error", err))
 					errChan <- fmt.Errorf("could not update transaction. err: %w", err)
 					return
 				}
 
-				slog.Debug("process: could not process transaction", slog.Any("error", err))
+				s.stats.Record(previous, tx)
+				s.publish(ctx, tx)
+
+				slog.DebugContext(ctx, "process: could not process transaction", slog.Any("error", err))
 				errChan <- fmt.Errorf("could not process transaction. err: %w", err)
 				return
 			}
 
-			slog.Info("process: transaction processed", slog.Any("response", res))
+			slog.InfoContext(ctx, "process: transaction processed", slog.Any("response", res))
 
 			// Update transaction with external ID and status
+			previous := tx.Status
 			tx.ExternalID = res.TransactionID
 			tx.Status = res.Status
 			tx.UpdatedAt = time.Now()
 
 			if err := s.repository.Update(&tx); err != nil {
-				slog.Debug("process: could not update transaction", slog.Any("error", err))
+				slog.DebugContext(ctx, "process: could not update transaction", slog.Any("error", err))
 				errChan <- fmt.Errorf("could not update transaction. err: %w", err)
 				return
 			}
+
+			if tx.Status != previous {
+				s.stats.Record(previous, tx)
+				s.publish(ctx, tx)
+			}
 		}
 	}()
 
 	return errChan
 }
+
+// route sends the transaction to the chosen gateways in order, failing over to the next one only when
+// a gateway could not be reached (connection error or open circuit), and records every attempt on the transaction.
+// The gateways are the ones the transaction was routed with, even if they have been reloaded since.
+func (s *transactionService) route(ctx context.Context, tx *model.Transaction, plan routingPlan) (model.GatewayResponse, error) {
+	var err error
+
+	// the rule, if any, selected the first gateway
+	rule := plan.decision.Rule
+
+	for _, id := range plan.decision.Gateways {
+		gateway := plan.gateways[id]
+
+		// The gateway always notifies this service, merchants are notified through webhooks
+		gatewayTx := *tx
+		gatewayTx.GatewayDetails.ID = id
+		gatewayTx.GatewayDetails.CallbackURL = s.callbackURL
+
+		gatewayCtx, span := tracer.Start(ctx, "PaymentGateway.ProcessTransaction", trace.WithAttributes(attribute.String("gateway.id", id)))
+		start := time.Now()
+
+		var res model.GatewayResponse
+		res, err = gateway.ProcessTransaction(gatewayCtx, gatewayTx)
+		tx.GatewayDetails.ID = id
+
+		if err == nil {
+			s.observeGateway(span, id, model.RoutingProcessed, start, nil)
+			tx.Routing = append(tx.Routing, routingAttempt(id, rule, model.RoutingProcessed, nil))
+			return res, nil
+		}
+
+		// the gateway may have processed the transaction, sending it elsewhere could charge the card twice
+		if !paymenthttp.NotSent(err) || ctx.Err() != nil {
+			s.observeGateway(span, id, model.RoutingFailed, start, err)
+			tx.Routing = append(tx.Routing, routingAttempt(id, rule, model.RoutingFailed, err))
+			return model.GatewayResponse{}, err
+		}
+
+		s.observeGateway(span, id, model.RoutingFailedOver, start, err)
+		tx.Routing = append(tx.Routing, routingAttempt(id, rule, model.RoutingFailedOver, err))
+		rule = ""
+		slog.InfoContext(ctx, "process: gateway unreachable, failing over", slog.String("gateway", id), slog.Any("error", err))
+	}
+
+	return model.GatewayResponse{}, err
+}
+
+// observeGateway observes the time the gateway took to answer since start and ends the span of the call
+// with its outcome
+func (s *transactionService) observeGateway(span trace.Span, id string, outcome model.RoutingOutcome, start time.Time, err error) {
+	s.metrics.gatewayLatency.WithLabelValues(id, string(outcome)).Observe(time.Since(start).Seconds())
+
+	span.SetAttributes(attribute.String("routing.outcome", string(outcome)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func routingAttempt(id, rule string, outcome model.RoutingOutcome, err error) model.RoutingAttempt {
+	attempt := model.RoutingAttempt{
+		GatewayID:   id,
+		Outcome:     outcome,
+		Rule:        rule,
+		AttemptedAt: time.Now(),
+	}
+
+	if err != nil {
+		attempt.Reason = err.Error()
+	}
+
+	return attempt
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"sync"
+
+	"go-payment-service/pkg/model"
+)
+
+// gatewayStats counts the outcomes of the transactions per gateway, to compare their approval rates
+type gatewayStats struct {
+	mu     sync.Mutex
+	counts map[string]*model.GatewayStats
+}
+
+// newGatewayStats creates new gateway stats
+func newGatewayStats() *gatewayStats {
+	return &gatewayStats{
+		counts: make(map[string]*model.GatewayStats),
+	}
+}
+
+// Record counts the transaction outcome once it reaches a terminal status from a non-terminal one
+func (s *gatewayStats) Record(previous model.TransactionStatus, tx model.Transaction) {
+	if previous.IsTerminal() || !tx.Status.IsTerminal() || tx.GatewayDetails.ID == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts, exists := s.counts[tx.GatewayDetails.ID]
+	if !exists {
+		counts = &model.GatewayStats{}
+		s.counts[tx.GatewayDetails.ID] = counts
+	}
+
+	if tx.Status == model.Succeeded {
+		counts.Succeeded++
+	} else {
+		counts.Failed++
+	}
+}
+
+// Get returns the stats of the gateway
+func (s *gatewayStats) Get(id string) model.GatewayStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts, exists := s.counts[id]
+	if !exists {
+		return model.GatewayStats{}
+	}
+
+	stats := *counts
+	if completed := stats.Succeeded + stats.Failed; completed > 0 {
+		stats.SuccessRate = float64(stats.Succeeded) / float64(completed)
+	}
+
+	return stats
+}
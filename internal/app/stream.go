@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	paymenthttp "go-payment-service/pkg/http"
+)
+
+const (
+	// streamKeepAliveInterval is how often a comment is sent to keep idle event streams open
+	streamKeepAliveInterval = 15 * time.Second
+	// defaultWaitTimeout is how long a long-poll request waits when no timeout is given
+	defaultWaitTimeout = 30 * time.Second
+	// maxWaitTimeout is the longest a long-poll request is allowed to wait
+	maxWaitTimeout = 60 * time.Second
+	// waitWriteMargin is the time left to write the response of a long-poll request once the wait is over
+	waitWriteMargin = 10 * time.Second
+)
+
+// streamTransaction emits a server-sent event with the transaction every time its status changes,
+// closing the stream once a terminal status is reached
+func (h *handler) streamTransaction(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get(paymenthttp.HeaderContentType)
+
+	// get transaction ID from path
+	id := r.PathValue("id")
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// watch transaction
+	updates, err := h.service.Watch(ctx, id)
+	if err != nil {
+		slog.DebugContext(r.Context(), "failed to watch transaction", slog.Any("error", err))
+		h.serviceErrorResponse(w, r, contentType, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	clearDeadlines(rc)
+
+	w.Header().Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeEventStream)
+	w.Header().Set(paymenthttp.HeaderCacheControl, "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for event := 1; ; {
+		select {
+		case tx, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(tx.Masked())
+			if err != nil {
+				slog.DebugContext(r.Context(), "failed to encode transaction event", slog.Any("error", err))
+				return
+			}
+
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: status\ndata: %s\n\n", event, data); err != nil {
+				return
+			}
+
+			event++
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			slog.DebugContext(r.Context(), "failed to flush transaction event", slog.Any("error", err))
+			return
+		}
+	}
+}
+
+// parseWaitForTerminal validates the long-poll query parameters (waitFor=terminal&timeout=20s)
+// and returns how long to wait
+func parseWaitForTerminal(query url.Values) (time.Duration, error) {
+	if waitFor := query.Get("waitFor"); waitFor != "terminal" {
+		return 0, fmt.Errorf("unsupported waitFor %q, expected terminal", waitFor)
+	}
+
+	if !query.Has("timeout") {
+		return defaultWaitTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(query.Get("timeout"))
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q, expected a positive duration such as 20s", query.Get("timeout"))
+	}
+
+	return min(timeout, maxWaitTimeout), nil
+}
+
+// waitForTerminal blocks until the transaction reaches a terminal status, the timeout elapses,
+// the client goes away or the server shuts down, relying on status change notifications
+func (h *handler) waitForTerminal(ctx context.Context, id string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	updates, err := h.service.Watch(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// the channel is closed on terminal status, timeout or shutdown
+	for range updates {
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"go-payment-service/internal/config"
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+	"go-payment-service/test/emulator"
+)
+
+type TestTracingSuite struct {
+	suite.Suite
+}
+
+func (suite *TestTracingSuite) TestTraceContextPropagation() {
+	// the package tracers delegate to the first global provider, so it is only set here
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	gatewayEmulator := emulator.StartWithCallbackSecret(testCallbackSecret)
+	defer gatewayEmulator.Close()
+
+	cfg := config.Gateway{ID: "gatewayA", Kind: "gatewayA", Endpoint: gatewayEmulator.URL}
+	gateway, err := newGateway(cfg, nil)
+	suite.Require().NoError(err)
+
+	webhooks := newWebhookService(newMemoryWebhookRepository(), []byte("secret"))
+	router := newGatewayRouter(map[string]PaymentGateway{"gatewayA": gateway}, nil)
+	router.setCallbackSecrets(map[string]string{"gatewayA": testCallbackSecret})
+	service := newTransactionService(&sync.WaitGroup{}, router, newMemoryTransactionRepository(), newTransactionBroker(), webhooks)
+	h := newHandler(service, webhooks, newHealthChecker())
+
+	server := httptest.NewServer(Logging(h.mux))
+	defer server.Close()
+	service.callbackURL = server.URL + "/callback"
+
+	b, err := json.Marshal(model.DepositRequest{BaseRequest: model.BaseRequest{
+		Amount:         model.Money{Amount: 10, Currency: "USD"},
+		CardDetails:    model.CardDetails{Number: "4111111111111111", Name: "John Doe", ExpiryMonth: 12, ExpiryYear: 2030, CVV: "123"},
+		GatewayDetails: model.GatewayDetails{ID: "gatewayA"},
+	}})
+	suite.Require().NoError(err)
+
+	// the caller's trace
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/deposit", bytes.NewReader(b))
+	suite.Require().NoError(err)
+	req.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	resp, err := http.DefaultClient.Do(req)
+	suite.Require().NoError(err)
+	resp.Body.Close()
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	spanNames := func() []string {
+		var names []string
+		for _, span := range recorder.Ended() {
+			if span.SpanContext().TraceID().String() == traceID {
+				names = append(names, span.Name())
+			}
+		}
+		return names
+	}
+
+	// the gateway notifies the transaction update asynchronously, in the same trace
+	suite.Eventually(func() bool {
+		return slices.Contains(spanNames(), "POST /callback")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	names := spanNames()
+	for _, expected := range []string{"POST /deposit", "transactionService.process", "PaymentGateway.ProcessTransaction", "HTTP POST", "emulator"} {
+		suite.Contains(names, expected)
+	}
+}
+
+func TestTestTracingSuite(t *testing.T) {
+	suite.Run(t, new(TestTracingSuite))
+}
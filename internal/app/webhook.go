@@ -0,0 +1,424 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-payment-service/internal/config"
+	paymenthttp "go-payment-service/pkg/http"
+	"go-payment-service/pkg/model"
+	"go-payment-service/pkg/webhook"
+)
+
+const (
+	// webhookPollInterval is how often the delivery queue is checked for due deliveries
+	webhookPollInterval = time.Second
+	// webhookBatchSize is the maximum number of deliveries dispatched at once
+	webhookBatchSize = 20
+	// webhookConcurrency is the maximum number of deliveries sent in parallel
+	webhookConcurrency = 4
+	// webhookTimeout is the timeout of a single delivery attempt
+	webhookTimeout = 10 * time.Second
+)
+
+var (
+	// ErrInvalidCallbackURL is returned for merchant callback URLs the webhooks must not be sent to
+	ErrInvalidCallbackURL = errors.New("invalid callback URL")
+	// ErrWebhookSecretMissing is returned when there is no secret to sign the webhooks of a merchant with
+	ErrWebhookSecretMissing = errors.New("no webhook secret")
+)
+
+// WebhookService delivers transaction status notifications to merchants
+type WebhookService interface {
+	// CheckURL returns ErrInvalidCallbackURL when the webhooks can not be sent to the URL
+	CheckURL(rawURL string) error
+	Enqueue(ctx context.Context, tx model.Transaction) error
+	ListDeadLetters(ctx context.Context) []model.WebhookDelivery
+	Replay(ctx context.Context, id string) (*model.WebhookDelivery, error)
+}
+
+// webhookService queues signed notifications to the merchant callback URL and delivers them
+// in the background, retrying with exponential backoff until they succeed or are dead-lettered
+type webhookService struct {
+	client     *http.Client
+	repository WebhookRepository
+	// secret signs the webhooks of the transactions without merchant, when authentication is disabled
+	secret []byte
+	// secrets sign the webhooks of each merchant, by merchant ID, so merchants can't forge the ones of the others
+	secrets     map[string][]byte
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	// allowPrivateURLs accepts http URLs and loopback or private network addresses, for local development
+	allowPrivateURLs bool
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	// cancel aborts the deliveries in progress, set by Start
+	cancel context.CancelFunc
+}
+
+// newWebhookService creates a new webhook service signing the webhooks without merchant with the secret,
+// deliveries start once Start is called
+func newWebhookService(repository WebhookRepository, secret []byte) *webhookService {
+	s := &webhookService{
+		repository:  repository,
+		secret:      secret,
+		maxAttempts: 10,               // Dead-letter after 10 attempts (~85 minutes)
+		baseDelay:   5 * time.Second,  // Wait 5 seconds before the first retry
+		maxDelay:    60 * time.Minute, // Never wait more than 1 hour between retries
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	// the addresses are checked once resolved, so host names pointing to internal networks are rejected too,
+	// and the requests are never sent through a proxy which would connect on their behalf
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: s.checkDialAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	s.client = &http.Client{Timeout: webhookTimeout, Transport: transport}
+
+	return s
+}
+
+// CheckURL accepts https URLs with a host name or a public IP address, and any http(s) URL when private URLs are allowed
+func (s *webhookService) CheckURL(rawURL string) error {
+	if rawURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" || u.User != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("%w: must be an absolute http(s) URL without credentials", ErrInvalidCallbackURL)
+	}
+
+	if s.allowPrivateURLs {
+		return nil
+	}
+
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: must use https", ErrInvalidCallbackURL)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s is not a public host", ErrInvalidCallbackURL, host)
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+		return fmt.Errorf("%w: %s is not a public address", ErrInvalidCallbackURL, host)
+	}
+
+	return nil
+}
+
+// checkDialAddress prevents connecting to loopback, private or link-local addresses, unless private URLs are allowed
+func (s *webhookService) checkDialAddress(network, address string, _ syscall.RawConn) error {
+	if s.allowPrivateURLs {
+		return nil
+	}
+
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCallbackURL, err)
+	}
+
+	if !publicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s is not a public address", ErrInvalidCallbackURL, addrPort.Addr())
+	}
+
+	return nil
+}
+
+// publicAddr reports whether the address is a global unicast address outside the private networks
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// Enqueue queues a notification of the transaction status to the merchant callback URL, if any
+func (s *webhookService) Enqueue(ctx context.Context, tx model.Transaction) error {
+	if tx.GatewayDetails.CallbackURL == "" {
+		return nil
+	}
+
+	if err := s.CheckURL(tx.GatewayDetails.CallbackURL); err != nil {
+		return err
+	}
+
+	if _, err := s.signingSecret(tx.MerchantID); err != nil {
+		return err
+	}
+
+	d := model.WebhookDelivery{
+		ID:         uuid.New().String(),
+		MerchantID: tx.MerchantID,
+		URL:        tx.GatewayDetails.CallbackURL,
+		Event: model.WebhookEvent{
+			ID:              uuid.New().String(),
+			Type:            model.TransactionStatusChanged,
+			TransactionID:   tx.ID,
+			TransactionType: tx.Type,
+			Status:          tx.Status,
+			Amount:          tx.Amount,
+			GatewayID:       tx.GatewayDetails.ID,
+			OccurredAt:      time.Now(),
+		},
+		Status:        model.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+
+	if err := s.repository.Create(&d); err != nil {
+		return fmt.Errorf("could not enqueue webhook delivery: %w", err)
+	}
+
+	s.notify()
+
+	return nil
+}
+
+// ListDeadLetters returns the deliveries of the merchant of the request that exhausted their attempts
+func (s *webhookService) ListDeadLetters(ctx context.Context) []model.WebhookDelivery {
+	merchantID := merchantFromContext(ctx).ID
+
+	var deliveries []model.WebhookDelivery
+	for _, d := range s.repository.ListByStatus(model.DeliveryDead) {
+		if d.MerchantID == merchantID {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	return deliveries
+}
+
+// Replay queues the delivery of the merchant of the request again for immediate delivery, resetting its attempts.
+// The deliveries of other merchants are not found.
+func (s *webhookService) Replay(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	d, err := s.repository.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.MerchantID != merchantFromContext(ctx).ID {
+		return nil, fmt.Errorf("webhook delivery %s: %w", id, ErrDeliveryNotFound)
+	}
+
+	d.Status = model.DeliveryPending
+	d.Attempts = 0
+	d.LastError = ""
+	d.NextAttemptAt = time.Now()
+
+	if err := s.repository.Update(d); err != nil {
+		return nil, fmt.Errorf("could not replay webhook delivery: %w", err)
+	}
+
+	s.notify()
+
+	return d, nil
+}
+
+// Start delivers the queued webhooks in the background until Stop is called
+func (s *webhookService) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	go func() {
+		defer close(s.done)
+		defer cancel()
+
+		ticker := time.NewTicker(webhookPollInterval)
+		defer ticker.Stop()
+
+		for {
+			s.dispatch(ctx)
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// Stop stops delivering webhooks, waiting for the deliveries in progress until ctx is done. They are then
+// aborted, left pending for a later attempt, and the error of ctx is returned.
+func (s *webhookService) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		if s.cancel != nil {
+			s.cancel()
+		}
+
+		return ctx.Err()
+	}
+}
+
+// Flush sends the deliveries due now, once the delivery loop is stopped, until none is left or ctx is done,
+// and returns the number of deliveries still pending (failed or scheduled later)
+func (s *webhookService) Flush(ctx context.Context) int {
+	for ctx.Err() == nil && len(s.repository.Due(time.Now(), 1)) > 0 {
+		s.dispatch(ctx)
+	}
+
+	return len(s.repository.ListByStatus(model.DeliveryPending))
+}
+
+// notify wakes up the delivery loop without blocking
+func (s *webhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch sends the due deliveries, waiting for all of them to complete
+func (s *webhookService) dispatch(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, webhookConcurrency)
+
+	for _, d := range s.repository.Due(time.Now(), webhookBatchSize) {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(d model.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			s.deliver(ctx, d)
+		}(d)
+	}
+
+	wg.Wait()
+}
+
+// deliver makes a delivery attempt and schedules the next one on failure
+func (s *webhookService) deliver(ctx context.Context, d model.WebhookDelivery) {
+	d.Attempts++
+
+	if err := s.send(ctx, d); err != nil {
+		// an aborted attempt is not counted, the delivery stays pending as it was
+		if ctx.Err() != nil {
+			slog.Debug("webhook: delivery aborted", slog.String("delivery-id", d.ID), slog.Any("error", err))
+			return
+		}
+
+		d.LastError = err.Error()
+
+		if d.Attempts >= s.maxAttempts {
+			d.Status = model.DeliveryDead
+			slog.Error("webhook: delivery dead-lettered", slog.String("delivery-id", d.ID), slog.Any("error", err))
+		} else {
+			d.NextAttemptAt = time.Now().Add(s.retryDelay(d.Attempts))
+			slog.Debug("webhook: delivery failed", slog.String("delivery-id", d.ID), slog.Int("attempts", d.Attempts), slog.Any("error", err))
+		}
+	} else {
+		d.Status = model.DeliverySucceeded
+		d.LastError = ""
+		d.DeliveredAt = time.Now()
+		slog.Debug("webhook: delivery succeeded", slog.String("delivery-id", d.ID), slog.Int("attempts", d.Attempts))
+	}
+
+	if err := s.repository.Update(&d); err != nil {
+		slog.Error("webhook: could not update delivery", slog.String("delivery-id", d.ID), slog.Any("error", err))
+	}
+}
+
+// send posts the signed event to the merchant URL, any non-2xx response is a failure
+func (s *webhookService) send(ctx context.Context, d model.WebhookDelivery) error {
+	secret, err := s.signingSecret(d.MerchantID)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set(paymenthttp.HeaderContentType, paymenthttp.MIMETypeJSON)
+	req.Header.Set(webhook.HeaderDeliveryID, d.ID)
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(secret, time.Now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("merchant returned status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// signingSecret returns the secret the webhooks of the merchant are signed with, the service's own secret
+// for the transactions without merchant
+func (s *webhookService) signingSecret(merchantID string) ([]byte, error) {
+	if merchantID == "" && s.secret != nil {
+		return s.secret, nil
+	}
+
+	secret, exists := s.secrets[merchantID]
+	if !exists {
+		return nil, fmt.Errorf("merchant %q: %w", merchantID, ErrWebhookSecretMissing)
+	}
+
+	return secret, nil
+}
+
+// webhookSecrets returns the webhook secrets of the merchants, by merchant ID
+func webhookSecrets(merchants []config.Merchant) map[string][]byte {
+	secrets := make(map[string][]byte, len(merchants))
+	for _, m := range merchants {
+		secrets[m.ID] = []byte(m.WebhookSecret)
+	}
+
+	return secrets
+}
+
+// retryDelay returns the exponential backoff delay after the given number of attempts
+func (s *webhookService) retryDelay(attempts int) time.Duration {
+	delay := s.baseDelay
+	for i := 1; i < attempts && delay < s.maxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, s.maxDelay)
+}
@@ -0,0 +1,307 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go-payment-service/pkg/model"
+)
+
+// ErrDeliveryNotFound is returned when a webhook delivery does not exist in the repository
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+// WebhookRepository defines the methods for the webhook delivery queue and its dead-letter store
+type WebhookRepository interface {
+	Create(d *model.WebhookDelivery) error
+	GetByID(id string) (*model.WebhookDelivery, error)
+	// Due returns the pending deliveries whose next attempt is due, oldest first. A delivery is not due while an older
+	// delivery of the same transaction is pending, so the events of a transaction are delivered in order.
+	Due(now time.Time, limit int) []model.WebhookDelivery
+	// ListByStatus returns the deliveries with the given status, oldest first
+	ListByStatus(status model.WebhookDeliveryStatus) []model.WebhookDelivery
+	Update(d *model.WebhookDelivery) error
+}
+
+// memoryWebhookRepository represents an in-memory repository for webhook deliveries.
+type memoryWebhookRepository struct {
+	mu         sync.RWMutex
+	deliveries map[string]model.WebhookDelivery
+}
+
+// newMemoryWebhookRepository creates a new in-memory webhook repository.
+func newMemoryWebhookRepository() WebhookRepository {
+	return &memoryWebhookRepository{
+		deliveries: make(map[string]model.WebhookDelivery),
+	}
+}
+
+// Create adds a new delivery to the repository.
+func (r *memoryWebhookRepository) Create(d *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.deliveries[d.ID]; exists {
+		return fmt.Errorf("webhook delivery %s already exists", d.ID)
+	}
+
+	d.CreatedAt = time.Now()
+	r.deliveries[d.ID] = *d
+
+	return nil
+}
+
+// GetByID retrieves a delivery by its ID.
+func (r *memoryWebhookRepository) GetByID(id string) (*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d, exists := r.deliveries[id]
+	if !exists {
+		return nil, fmt.Errorf("webhook delivery %s: %w", id, ErrDeliveryNotFound)
+	}
+
+	return &d, nil
+}
+
+// Due returns the oldest pending delivery of each transaction, when its next attempt is due.
+func (r *memoryWebhookRepository) Due(now time.Time, limit int) []model.WebhookDelivery {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	oldest := make(map[string]model.WebhookDelivery)
+	for _, d := range r.deliveries {
+		if d.Status != model.DeliveryPending {
+			continue
+		}
+
+		if first, exists := oldest[d.Event.TransactionID]; !exists || d.CreatedAt.Before(first.CreatedAt) {
+			oldest[d.Event.TransactionID] = d
+		}
+	}
+
+	var due []model.WebhookDelivery
+	for _, d := range oldest {
+		if !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+
+	sortDeliveries(due)
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due
+}
+
+// ListByStatus returns the deliveries with the given status.
+func (r *memoryWebhookRepository) ListByStatus(status model.WebhookDeliveryStatus) []model.WebhookDelivery {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var list []model.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == status {
+			list = append(list, d)
+		}
+	}
+
+	sortDeliveries(list)
+
+	return list
+}
+
+// Update updates the delivery.
+func (r *memoryWebhookRepository) Update(d *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.deliveries[d.ID]; !exists {
+		return fmt.Errorf("webhook delivery %s: %w", d.ID, ErrDeliveryNotFound)
+	}
+
+	d.UpdatedAt = time.Now()
+	r.deliveries[d.ID] = *d
+
+	return nil
+}
+
+func sortDeliveries(deliveries []model.WebhookDelivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+	})
+}
+
+// newWebhookRepository returns the repository journaled to the file, or an in-memory one, losing the deliveries
+// on restart, when no file is set
+func newWebhookRepository(path string) (WebhookRepository, error) {
+	if path == "" {
+		slog.Warn("server: no webhook queue file configured, the deliveries are kept in memory only")
+		return newMemoryWebhookRepository(), nil
+	}
+
+	return newFileWebhookRepository(path)
+}
+
+// fileWebhookRepository is a webhook repository keeping the deliveries in memory and appending every change to
+// a journal file, so the queue and the dead letters survive restarts. The journal is compacted when opened.
+type fileWebhookRepository struct {
+	*memoryWebhookRepository
+
+	// journalMu serializes the changes, so they are journaled in the order they are applied
+	journalMu sync.Mutex
+	journal   *os.File
+}
+
+// newFileWebhookRepository opens the webhook repository journaled to the file, creating it if needed
+func newFileWebhookRepository(path string) (*fileWebhookRepository, error) {
+	deliveries, err := readWebhookJournal(path)
+	if err != nil {
+		return nil, err
+	}
+
+	journal, err := compactWebhookJournal(path, deliveries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileWebhookRepository{
+		memoryWebhookRepository: &memoryWebhookRepository{deliveries: deliveries},
+		journal:                 journal,
+	}, nil
+}
+
+// Create adds a new delivery to the repository and journals it.
+func (r *fileWebhookRepository) Create(d *model.WebhookDelivery) error {
+	r.journalMu.Lock()
+	defer r.journalMu.Unlock()
+
+	if err := r.memoryWebhookRepository.Create(d); err != nil {
+		return err
+	}
+
+	return r.append(d)
+}
+
+// Update updates the delivery and journals it.
+func (r *fileWebhookRepository) Update(d *model.WebhookDelivery) error {
+	r.journalMu.Lock()
+	defer r.journalMu.Unlock()
+
+	if err := r.memoryWebhookRepository.Update(d); err != nil {
+		return err
+	}
+
+	return r.append(d)
+}
+
+// Close closes the journal file
+func (r *fileWebhookRepository) Close() error {
+	r.journalMu.Lock()
+	defer r.journalMu.Unlock()
+
+	return r.journal.Close()
+}
+
+// append writes the delivery at the end of the journal and syncs it to disk
+func (r *fileWebhookRepository) append(d *model.WebhookDelivery) error {
+	line, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook delivery %s: %w", d.ID, err)
+	}
+
+	if _, err := r.journal.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to journal webhook delivery %s: %w", d.ID, err)
+	}
+
+	if err := r.journal.Sync(); err != nil {
+		return fmt.Errorf("failed to journal webhook delivery %s: %w", d.ID, err)
+	}
+
+	return nil
+}
+
+// readWebhookJournal returns the last state of the deliveries journaled to the file, none when it does not exist.
+// A truncated last line, left by a crash while it was written, is skipped.
+func readWebhookJournal(path string) (map[string]model.WebhookDelivery, error) {
+	deliveries := make(map[string]model.WebhookDelivery)
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return deliveries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook journal: %w", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+
+		var d model.WebhookDelivery
+		if err := json.Unmarshal(line, &d); err != nil {
+			if i == len(lines)-1 && !bytes.HasSuffix(b, []byte("\n")) {
+				slog.Warn("webhook: truncated journal entry skipped", slog.String("path", path))
+				continue
+			}
+
+			return nil, fmt.Errorf("failed to read webhook journal: line %d: %w", i+1, err)
+		}
+
+		deliveries[d.ID] = d
+	}
+
+	return deliveries, nil
+}
+
+// compactWebhookJournal atomically replaces the journal with one entry per delivery, oldest first,
+// and returns it opened for appending
+func compactWebhookJournal(path string, deliveries map[string]model.WebhookDelivery) (*os.File, error) {
+	list := make([]model.WebhookDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		list = append(list, d)
+	}
+	sortDeliveries(list)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to compact webhook journal: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, d := range list {
+		if err := enc.Encode(d); err != nil {
+			_ = tmp.Close()
+			return nil, fmt.Errorf("failed to compact webhook journal: %w", err)
+		}
+	}
+
+	if err := errors.Join(w.Flush(), tmp.Sync(), tmp.Close()); err != nil {
+		return nil, fmt.Errorf("failed to compact webhook journal: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to compact webhook journal: %w", err)
+	}
+
+	journal, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open webhook journal: %w", err)
+	}
+
+	return journal, nil
+}
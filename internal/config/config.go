@@ -0,0 +1,515 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the service configuration, see Load for how it is built
+type Config struct {
+	// Env is the environment the service runs in, "development" enables debug logs
+	Env         string           `yaml:"env" json:"env"`
+	HTTP        HTTPConfig       `yaml:"http" json:"http"`
+	GRPC        GRPCConfig       `yaml:"grpc" json:"grpc"`
+	Processing  ProcessingConfig `yaml:"processing" json:"processing"`
+	CallbackURL string           `yaml:"callbackUrl" json:"callbackUrl"` // Defaults to the local /callback endpoint
+	Webhooks    WebhooksConfig   `yaml:"webhooks" json:"webhooks"`
+	Routing     RoutingConfig    `yaml:"routing" json:"routing"`
+	Gateways    []Gateway        `yaml:"gateways" json:"gateways"`
+	Merchants   []Merchant       `yaml:"merchants" json:"merchants"` // Required unless auth.disabled is set
+	Auth        AuthConfig       `yaml:"auth" json:"auth"`
+	Reload      ReloadConfig     `yaml:"reload" json:"reload"`
+	Shutdown    ShutdownConfig   `yaml:"shutdown" json:"shutdown"`
+	Tracing     TracingConfig    `yaml:"tracing" json:"tracing"`
+
+	// File is the configuration file it was loaded from, if any
+	File string `yaml:"-" json:"-"`
+}
+
+// Trace exporters
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+// TracingConfig configures the OpenTelemetry traces of the service
+type TracingConfig struct {
+	// Exporter is where the spans are exported: none, stdout or otlp
+	Exporter string `yaml:"exporter" json:"exporter"`
+	// Endpoint is the URL of the OTLP/HTTP collector, the OTEL_EXPORTER_OTLP_* variables or localhost:4318 when empty
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	// SampleRatio is the share of the traces started by the service that are sampled, between 0 and 1
+	SampleRatio float64 `yaml:"sampleRatio" json:"sampleRatio"`
+	// ServiceName identifies the service in the traces
+	ServiceName string `yaml:"serviceName" json:"serviceName"`
+}
+
+// ShutdownConfig configures the graceful shutdown of the service
+type ShutdownConfig struct {
+	// Delay is how long the service keeps serving once marked not ready, for load balancers to stop sending traffic
+	Delay Duration `yaml:"delay" json:"delay"`
+	// Timeout is the deadline to drain the in-flight requests, transactions and webhooks
+	Timeout Duration `yaml:"timeout" json:"timeout"`
+}
+
+// AuthConfig configures the authentication of the API
+type AuthConfig struct {
+	// Disabled serves the API without authentication, for local development only; no merchant may be configured
+	Disabled bool `yaml:"disabled" json:"disabled"`
+}
+
+// ReloadConfig configures the reload of the configuration without restart, see Watch
+type ReloadConfig struct {
+	// Interval is how often the configuration and routing rules files are checked for changes, never when 0
+	Interval Duration `yaml:"interval" json:"interval"`
+}
+
+// HTTPConfig configures the HTTP server
+type HTTPConfig struct {
+	Port              string   `yaml:"port" json:"port"`
+	ReadHeaderTimeout Duration `yaml:"readHeaderTimeout" json:"readHeaderTimeout"`
+	// ReadTimeout bounds the reading of a request, body included
+	ReadTimeout Duration `yaml:"readTimeout" json:"readTimeout"`
+	// WriteTimeout bounds the writing of a response, the transaction streams and exports are not bounded
+	WriteTimeout Duration `yaml:"writeTimeout" json:"writeTimeout"`
+	// IdleTimeout is how long a keep-alive connection waits for the next request
+	IdleTimeout Duration `yaml:"idleTimeout" json:"idleTimeout"`
+	// MaxBodyBytes is the size limit of the request bodies
+	MaxBodyBytes int64 `yaml:"maxBodyBytes" json:"maxBodyBytes"`
+	// RouteMaxBodyBytes overrides the size limit of the request bodies by route, e.g. "POST /callback"
+	RouteMaxBodyBytes map[string]int64 `yaml:"routeMaxBodyBytes,omitempty" json:"routeMaxBodyBytes,omitempty"`
+}
+
+// GRPCConfig configures the gRPC server
+type GRPCConfig struct {
+	Port string `yaml:"port" json:"port"`
+	// ShutdownTimeout is how long in-flight RPCs are waited for on shutdown before they are cancelled
+	ShutdownTimeout Duration `yaml:"shutdownTimeout" json:"shutdownTimeout"`
+}
+
+// ProcessingConfig configures the processing of the transactions
+type ProcessingConfig struct {
+	// Timeout is the deadline for the gateways to accept a transaction, failovers included
+	Timeout Duration `yaml:"timeout" json:"timeout"`
+}
+
+// WebhooksConfig configures the merchant webhooks
+type WebhooksConfig struct {
+	// Secret signs the webhooks when authentication is disabled, a random one (which merchants can't know) is used
+	// when empty. The merchants sign theirs with their own secret.
+	Secret string `yaml:"secret" json:"secret"`
+	// AllowPrivateURLs accepts http and loopback or private network webhook URLs, for local development only
+	AllowPrivateURLs bool `yaml:"allowPrivateUrls" json:"allowPrivateUrls"`
+	// QueueFile journals the webhook deliveries, so they survive restarts. They are kept in memory only when empty.
+	QueueFile string `yaml:"queueFile" json:"queueFile"`
+}
+
+// RoutingConfig configures how transactions are routed between gateways
+type RoutingConfig struct {
+	RulesFile string          `yaml:"rulesFile" json:"rulesFile"` // JSON routing rules, none when empty
+	Priority  []string        `yaml:"priority" json:"priority"`   // Gateway IDs from the most to the least preferred
+	Weights   []GatewayWeight `yaml:"weights" json:"weights"`     // Initial weighted split, disabled when empty
+	// FingerprintSecret keys the card fingerprints assigning the cards to a gateway in the weighted split,
+	// a random one is used when empty, so the cards may change gateway on restart
+	FingerprintSecret string `yaml:"fingerprintSecret" json:"fingerprintSecret"`
+}
+
+// GatewayWeight is the share of the traffic sent to a gateway, in percent
+type GatewayWeight struct {
+	GatewayID string `yaml:"gatewayId" json:"gatewayId"`
+	Weight    int    `yaml:"weight" json:"weight"`
+}
+
+// Gateway declares a payment gateway, the zero values of the client settings keep their defaults
+type Gateway struct {
+	ID   string `yaml:"id" json:"id"`
+	Kind string `yaml:"kind" json:"kind"` // Adapter kind, e.g. gatewayA
+	// Endpoint is the base URL of the gateway, the in-process emulator is used when empty
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	// Credentials is the name of the environment variable holding the gateway API key, if it needs one
+	Credentials string `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+	// CallbackCredentials is the name of the environment variable holding the secret the gateway authenticates its
+	// callbacks with, required with an endpoint. The gateways on the emulator share a random secret with it.
+	CallbackCredentials string   `yaml:"callbackCredentials,omitempty" json:"callbackCredentials,omitempty"`
+	Enabled             *bool    `yaml:"enabled,omitempty" json:"enabled,omitempty"` // Enabled by default
+	Timeout             Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	MaxRetries          *uint64  `yaml:"maxRetries,omitempty" json:"maxRetries,omitempty"`
+	BreakerFailures     uint32   `yaml:"breakerFailures,omitempty" json:"breakerFailures,omitempty"`
+	BreakerTimeout      Duration `yaml:"breakerTimeout,omitempty" json:"breakerTimeout,omitempty"`
+	MaxConns            int      `yaml:"maxConns,omitempty" json:"maxConns,omitempty"`
+
+	// APIKey is the API key resolved from the credentials by Load
+	APIKey string `yaml:"-" json:"-"`
+	// CallbackSecret is the secret resolved from the callback credentials by Load
+	CallbackSecret string `yaml:"-" json:"-"`
+}
+
+// IsEnabled reports whether transactions can be sent to the gateway
+func (g Gateway) IsEnabled() bool {
+	return g.Enabled == nil || *g.Enabled
+}
+
+// Merchant declares a client of the API, authenticated with its API key
+type Merchant struct {
+	ID   string `yaml:"id" json:"id"`
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// APIKeyHash is the hex-encoded SHA-256 hash of the API key, e.g. printf %s "$API_KEY" | sha256sum
+	APIKeyHash string `yaml:"apiKeyHash" json:"apiKeyHash"`
+	// Admin allows the merchant to call the admin routes, e.g. to adjust the routing weights
+	Admin bool `yaml:"admin,omitempty" json:"admin,omitempty"`
+	// WebhookCredentials is the name of the environment variable holding the secret the webhooks of the merchant
+	// are signed with, so a merchant can't verify, nor forge, the webhooks of the others
+	WebhookCredentials string `yaml:"webhookCredentials" json:"webhookCredentials"`
+	// Gateways are the contracts of the merchant with the gateways, its transactions are only sent to the
+	// gateways listed. All the gateways are used with their own credentials when empty.
+	Gateways []MerchantGateway `yaml:"gateways,omitempty" json:"gateways,omitempty"`
+
+	// WebhookSecret is the secret resolved from the webhook credentials by Load
+	WebhookSecret string `yaml:"-" json:"-"`
+}
+
+// MerchantGateway declares the contract of a merchant with a gateway
+type MerchantGateway struct {
+	ID string `yaml:"id" json:"id"` // ID of the gateway
+	// Credentials is the name of the environment variable holding the API key of the merchant with the gateway,
+	// required unless PlatformCredentials is set
+	Credentials string `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+	// PlatformCredentials sends the API key of the gateway instead, for the merchants without a key of their own
+	PlatformCredentials bool  `yaml:"platformCredentials,omitempty" json:"platformCredentials,omitempty"`
+	Enabled             *bool `yaml:"enabled,omitempty" json:"enabled,omitempty"` // Enabled by default
+	// MinAmount and MaxAmount narrow the amounts the gateway accepts for the merchant, no limit when 0
+	MinAmount float64 `yaml:"minAmount,omitempty" json:"minAmount,omitempty"`
+	MaxAmount float64 `yaml:"maxAmount,omitempty" json:"maxAmount,omitempty"`
+
+	// APIKey is the API key resolved from the credentials by Load
+	APIKey string `yaml:"-" json:"-"`
+}
+
+// IsEnabled reports whether the transactions of the merchant can be sent to the gateway
+func (g MerchantGateway) IsEnabled() bool {
+	return g.Enabled == nil || *g.Enabled
+}
+
+// Default returns the default configuration: gatewayA and gatewayB on the in-process emulator
+func Default() Config {
+	return Config{
+		HTTP: HTTPConfig{
+			Port:              "8080",
+			ReadHeaderTimeout: Duration(10 * time.Second),
+			ReadTimeout:       Duration(30 * time.Second),
+			WriteTimeout:      Duration(40 * time.Second),
+			IdleTimeout:       Duration(2 * time.Minute),
+			MaxBodyBytes:      1 << 20,
+			RouteMaxBodyBytes: map[string]int64{
+				"POST /callback": 64 << 10,
+			},
+		},
+		GRPC: GRPCConfig{
+			Port:            "9090",
+			ShutdownTimeout: Duration(10 * time.Second),
+		},
+		Processing: ProcessingConfig{
+			Timeout: Duration(30 * time.Second),
+		},
+		Routing: RoutingConfig{
+			Priority: []string{"gatewayA", "gatewayB"},
+		},
+		Reload: ReloadConfig{
+			Interval: Duration(5 * time.Second),
+		},
+		Shutdown: ShutdownConfig{
+			Timeout: Duration(30 * time.Second),
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingNone,
+			SampleRatio: 1,
+			ServiceName: "payment-service",
+		},
+		Gateways: []Gateway{
+			{ID: "gatewayA", Kind: "gatewayA"},
+			{ID: "gatewayB", Kind: "gatewayB"},
+		},
+	}
+}
+
+// Development reports whether the service runs in development
+func (c Config) Development() bool {
+	return c.Env == "development"
+}
+
+// Validate checks the configuration, including that the credentials resolved by Load are set
+func (c *Config) Validate() error {
+	var errs []error
+
+	ports := []struct {
+		name, value string
+	}{
+		{"http.port", c.HTTP.Port},
+		{"grpc.port", c.GRPC.Port},
+	}
+	for _, port := range ports {
+		if n, err := strconv.Atoi(port.value); err != nil || n < 1 || n > 65535 {
+			errs = append(errs, fmt.Errorf("%s: must be a port number, got %q", port.name, port.value))
+		}
+	}
+
+	timeouts := []struct {
+		name  string
+		value Duration
+	}{
+		{"http.readHeaderTimeout", c.HTTP.ReadHeaderTimeout},
+		{"http.readTimeout", c.HTTP.ReadTimeout},
+		{"http.writeTimeout", c.HTTP.WriteTimeout},
+		{"http.idleTimeout", c.HTTP.IdleTimeout},
+		{"grpc.shutdownTimeout", c.GRPC.ShutdownTimeout},
+		{"processing.timeout", c.Processing.Timeout},
+		{"shutdown.timeout", c.Shutdown.Timeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s: must be positive", timeout.name))
+		}
+	}
+
+	// the deposits and withdrawals respond once the gateways accepted them, within the processing timeout
+	if c.HTTP.WriteTimeout <= c.Processing.Timeout {
+		errs = append(errs, fmt.Errorf("http.writeTimeout: must be longer than processing.timeout (%s)", c.Processing.Timeout))
+	}
+
+	if c.HTTP.MaxBodyBytes <= 0 {
+		errs = append(errs, errors.New("http.maxBodyBytes: must be positive"))
+	}
+
+	for route, limit := range c.HTTP.RouteMaxBodyBytes {
+		if method, path, ok := strings.Cut(route, " "); !ok || method == "" || !strings.HasPrefix(path, "/") {
+			errs = append(errs, fmt.Errorf("http.routeMaxBodyBytes: route must be a method and a path, e.g. \"POST /callback\", got %q", route))
+		}
+		if limit <= 0 {
+			errs = append(errs, fmt.Errorf("http.routeMaxBodyBytes[%s]: must be positive", route))
+		}
+	}
+
+	if c.Reload.Interval < 0 {
+		errs = append(errs, errors.New("reload.interval: must not be negative"))
+	}
+
+	if c.Shutdown.Delay < 0 {
+		errs = append(errs, errors.New("shutdown.delay: must not be negative"))
+	}
+
+	if c.CallbackURL != "" && !validURL(c.CallbackURL) {
+		errs = append(errs, errors.New("callbackUrl: must be an http(s) URL"))
+	}
+
+	switch c.Tracing.Exporter {
+	case TracingNone, TracingStdout, TracingOTLP:
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter: must be one of %s, %s or %s, got %q", TracingNone, TracingStdout, TracingOTLP, c.Tracing.Exporter))
+	}
+
+	if c.Tracing.Endpoint != "" && !validURL(c.Tracing.Endpoint) {
+		errs = append(errs, errors.New("tracing.endpoint: must be an http(s) URL"))
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sampleRatio: must be between 0 and 1, got %g", c.Tracing.SampleRatio))
+	}
+
+	// the API is never left open by mistake
+	switch {
+	case len(c.Merchants) == 0 && !c.Auth.Disabled:
+		errs = append(errs, errors.New("merchants: at least one merchant is required, unless auth.disabled is set"))
+	case len(c.Merchants) > 0 && c.Auth.Disabled:
+		errs = append(errs, errors.New("auth.disabled: must not be set when merchants are configured"))
+	}
+
+	if len(c.Merchants) > 0 && c.Webhooks.Secret != "" {
+		errs = append(errs, errors.New("webhooks.secret: must not be set when merchants are configured, they have their own webhookCredentials"))
+	}
+
+	errs = append(errs, c.validateGateways()...)
+	errs = append(errs, c.validateMerchants()...)
+
+	return errors.Join(errs...)
+}
+
+// validateGateways checks every gateway has a unique ID, a kind, a valid endpoint and its credentials set,
+// and that at least one gateway is enabled
+func (c *Config) validateGateways() []error {
+	var errs []error
+
+	seen := make(map[string]bool, len(c.Gateways))
+	enabled := 0
+
+	for i := range c.Gateways {
+		gw := &c.Gateways[i]
+
+		if gw.ID == "" {
+			errs = append(errs, fmt.Errorf("gateways[%d]: id is required", i))
+		} else if seen[gw.ID] {
+			errs = append(errs, fmt.Errorf("gateways[%d]: duplicate id %q", i, gw.ID))
+		}
+		seen[gw.ID] = true
+
+		if gw.Kind == "" {
+			errs = append(errs, fmt.Errorf("gateways[%d] (%s): kind is required", i, gw.ID))
+		}
+
+		if gw.Endpoint != "" && !validURL(gw.Endpoint) {
+			errs = append(errs, fmt.Errorf("gateways[%d] (%s): endpoint must be an http(s) URL", i, gw.ID))
+		}
+
+		if gw.Credentials != "" && gw.APIKey == "" && gw.IsEnabled() {
+			errs = append(errs, fmt.Errorf("gateways[%d] (%s): credentials %s are not set", i, gw.ID, gw.Credentials))
+		}
+
+		switch {
+		case gw.CallbackCredentials != "":
+			if gw.CallbackSecret == "" && gw.IsEnabled() {
+				errs = append(errs, fmt.Errorf("gateways[%d] (%s): callback credentials %s are not set", i, gw.ID, gw.CallbackCredentials))
+			}
+		case gw.Endpoint != "" && gw.IsEnabled():
+			errs = append(errs, fmt.Errorf("gateways[%d] (%s): callbackCredentials are required with an endpoint", i, gw.ID))
+		}
+
+		if gw.IsEnabled() {
+			enabled++
+		}
+	}
+
+	if enabled == 0 {
+		errs = append(errs, errors.New("gateways: no gateway enabled"))
+	}
+
+	return errs
+}
+
+// validateMerchants checks every merchant has a unique ID, a unique SHA-256 API key hash, its webhook secret set
+// and valid contracts with configured gateways, with their credentials set unless they explicitly use the ones
+// of the gateway
+func (c *Config) validateMerchants() []error {
+	var errs []error
+
+	seen := make(map[string]bool, len(c.Merchants))
+	hashes := make(map[string]bool, len(c.Merchants))
+
+	gateways := make(map[string]bool, len(c.Gateways))
+	for _, gw := range c.Gateways {
+		gateways[gw.ID] = true
+	}
+
+	for i := range c.Merchants {
+		m := &c.Merchants[i]
+
+		if m.ID == "" {
+			errs = append(errs, fmt.Errorf("merchants[%d]: id is required", i))
+		} else if seen[m.ID] {
+			errs = append(errs, fmt.Errorf("merchants[%d]: duplicate id %q", i, m.ID))
+		}
+		seen[m.ID] = true
+
+		hash := strings.ToLower(m.APIKeyHash)
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+			errs = append(errs, fmt.Errorf("merchants[%d] (%s): apiKeyHash must be a hex-encoded SHA-256 hash", i, m.ID))
+		} else if hashes[hash] {
+			errs = append(errs, fmt.Errorf("merchants[%d] (%s): apiKeyHash is used by another merchant", i, m.ID))
+		}
+		hashes[hash] = true
+
+		switch {
+		case m.WebhookCredentials == "":
+			errs = append(errs, fmt.Errorf("merchants[%d] (%s): webhookCredentials are required", i, m.ID))
+		case m.WebhookSecret == "":
+			errs = append(errs, fmt.Errorf("merchants[%d] (%s): webhook credentials %s are not set", i, m.ID, m.WebhookCredentials))
+		}
+
+		contracts := make(map[string]bool, len(m.Gateways))
+		for j := range m.Gateways {
+			gw := &m.Gateways[j]
+
+			if !gateways[gw.ID] {
+				errs = append(errs, fmt.Errorf("merchants[%d] (%s): gateways[%d]: unknown gateway %q", i, m.ID, j, gw.ID))
+			} else if contracts[gw.ID] {
+				errs = append(errs, fmt.Errorf("merchants[%d] (%s): gateways[%d]: duplicate gateway %q", i, m.ID, j, gw.ID))
+			}
+			contracts[gw.ID] = true
+
+			if gw.MinAmount < 0 || gw.MaxAmount < 0 || (gw.MaxAmount != 0 && gw.MinAmount > gw.MaxAmount) {
+				errs = append(errs, fmt.Errorf("merchants[%d] (%s): gateways[%d] (%s): amounts must be positive, the minimum not above the maximum", i, m.ID, j, gw.ID))
+			}
+
+			switch {
+			case gw.Credentials != "" && gw.PlatformCredentials:
+				errs = append(errs, fmt.Errorf("merchants[%d] (%s): gateways[%d] (%s): credentials and platformCredentials are exclusive", i, m.ID, j, gw.ID))
+			case gw.Credentials != "":
+				if gw.APIKey == "" && gw.IsEnabled() {
+					errs = append(errs, fmt.Errorf("merchants[%d] (%s): gateways[%d] (%s): credentials %s are not set", i, m.ID, j, gw.ID, gw.Credentials))
+				}
+			case !gw.PlatformCredentials && gw.IsEnabled():
+				errs = append(errs, fmt.Errorf("merchants[%d] (%s): gateways[%d] (%s): credentials are required, unless platformCredentials is set", i, m.ID, j, gw.ID))
+			}
+		}
+	}
+
+	return errs
+}
+
+// validURL reports whether the value is an absolute http(s) URL
+func validURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Duration is a time.Duration written as a string in the configuration file, e.g. "5s"
+type Duration time.Duration
+
+// String returns the duration formatted like time.Duration
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string, e.g. \"5s\": %w", err)
+	}
+
+	return d.parse(s)
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.parse(node.Value)
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *Duration) parse(s string) error {
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
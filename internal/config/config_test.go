@@ -0,0 +1,269 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TestConfigSuite struct {
+	suite.Suite
+}
+
+func (suite *TestConfigSuite) TestLoad() {
+	cfg, err := Load([]string{"-config", filepath.Join("..", "..", "configs", "config.yaml")})
+	suite.Require().NoError(err)
+
+	suite.True(cfg.Development())
+	suite.True(cfg.Auth.Disabled)
+	suite.Equal(Duration(30*time.Second), cfg.Processing.Timeout)
+	suite.Equal([]GatewayWeight{{GatewayID: "gatewayA", Weight: 90}, {GatewayID: "gatewayB", Weight: 10}}, cfg.Routing.Weights)
+	suite.Require().Len(cfg.Gateways, 3)
+	suite.Equal(Duration(5*time.Second), cfg.Gateways[0].Timeout)
+	suite.Equal(uint64(3), *cfg.Gateways[0].MaxRetries)
+	suite.False(cfg.Gateways[2].IsEnabled())
+}
+
+func (suite *TestConfigSuite) TestPrecedence() {
+	path := filepath.Join(suite.T().TempDir(), "config.json")
+	suite.Require().NoError(os.WriteFile(path, []byte(`{
+		"http": {"port": "8000"},
+		"grpc": {"port": "9000"},
+		"processing": {"timeout": "20s"},
+		"gateways": [{"id": "gatewayA", "kind": "gatewayA", "timeout": "5s"}]
+	}`), 0o600))
+
+	suite.T().Setenv("CONFIG_FILE", path)
+	suite.T().Setenv("GRPC_PORT", "9001")
+	suite.T().Setenv("PROCESSING_TIMEOUT", "15s")
+	suite.T().Setenv("GATEWAYA_TIMEOUT", "2s")
+	suite.T().Setenv("GATEWAY_WEIGHTS", "gatewayA=100")
+	suite.T().Setenv("AUTH_DISABLED", "true")
+
+	cfg, err := Load([]string{"-grpc-port", "9002"})
+	suite.Require().NoError(err)
+
+	suite.Equal("8000", cfg.HTTP.Port, "file overrides the defaults")
+	suite.Equal(Duration(10*time.Second), cfg.HTTP.ReadHeaderTimeout, "defaults are kept when not in the file")
+	suite.Equal(Duration(15*time.Second), cfg.Processing.Timeout, "environment overrides the file")
+	suite.Equal("9002", cfg.GRPC.Port, "flags override the environment")
+	suite.Require().Len(cfg.Gateways, 1, "gateways of the file replace the default ones")
+	suite.Equal(Duration(2*time.Second), cfg.Gateways[0].Timeout)
+	suite.Equal([]GatewayWeight{{GatewayID: "gatewayA", Weight: 100}}, cfg.Routing.Weights)
+}
+
+func (suite *TestConfigSuite) TestInvalid() {
+	disabled := false
+
+	testCases := []struct {
+		name     string
+		given    func(cfg *Config)
+		expected []string
+	}{
+		{
+			name: "server",
+			given: func(cfg *Config) {
+				cfg.HTTP.Port = "http"
+				cfg.Processing.Timeout = 0
+				cfg.CallbackURL = "localhost/callback"
+				cfg.Tracing = TracingConfig{Exporter: "jaeger", Endpoint: "collector:4318", SampleRatio: 2}
+				cfg.HTTP.WriteTimeout = 0
+				cfg.HTTP.MaxBodyBytes = 0
+				cfg.HTTP.RouteMaxBodyBytes = map[string]int64{"/callback": -1}
+			},
+			expected: []string{
+				`http.port: must be a port number, got "http"`,
+				"processing.timeout: must be positive",
+				"callbackUrl: must be an http(s) URL",
+				`tracing.exporter: must be one of none, stdout or otlp, got "jaeger"`,
+				"tracing.endpoint: must be an http(s) URL",
+				"tracing.sampleRatio: must be between 0 and 1, got 2",
+				"http.writeTimeout: must be positive",
+				"http.maxBodyBytes: must be positive",
+				`http.routeMaxBodyBytes: route must be a method and a path, e.g. "POST /callback", got "/callback"`,
+				"http.routeMaxBodyBytes[/callback]: must be positive",
+			},
+		},
+		{
+			name: "gateways",
+			given: func(cfg *Config) {
+				cfg.Gateways = []Gateway{
+					{Kind: "gatewayA"},
+					{ID: "gatewayB"},
+					{ID: "gatewayB", Kind: "gatewayB", Endpoint: "gateway-b.example.com"},
+					{ID: "gatewayC", Kind: "gatewayA", Credentials: "CONFIG_TEST_API_KEY"},
+					{ID: "gatewayD", Kind: "gatewayA", Endpoint: "https://gateway-d.example.com"},
+					{ID: "gatewayE", Kind: "gatewayA", Endpoint: "https://gateway-e.example.com", CallbackCredentials: "CONFIG_TEST_CALLBACK_SECRET"},
+				}
+			},
+			expected: []string{
+				"gateways[0]: id is required",
+				"gateways[1] (gatewayB): kind is required",
+				`gateways[2]: duplicate id "gatewayB"`,
+				"gateways[2] (gatewayB): endpoint must be an http(s) URL",
+				"gateways[3] (gatewayC): credentials CONFIG_TEST_API_KEY are not set",
+				"gateways[4] (gatewayD): callbackCredentials are required with an endpoint",
+				"gateways[5] (gatewayE): callback credentials CONFIG_TEST_CALLBACK_SECRET are not set",
+			},
+		},
+		{
+			name: "merchants",
+			given: func(cfg *Config) {
+				hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+				cfg.Merchants = []Merchant{
+					{ID: "merchantA", APIKeyHash: hash, WebhookCredentials: "CONFIG_TEST_WEBHOOK_SECRET"},
+					{APIKeyHash: strings.ToUpper(hash)},
+					{ID: "merchantA", APIKeyHash: "secret"},
+					{ID: "merchantB", APIKeyHash: strings.Repeat("0", 64), Gateways: []MerchantGateway{
+						{ID: "gatewayZ"},
+						{ID: "gatewayA", MinAmount: 100, MaxAmount: 10},
+						{ID: "gatewayA", Credentials: "CONFIG_TEST_MERCHANT_API_KEY"},
+					}},
+					{ID: "merchantC", APIKeyHash: strings.Repeat("1", 64), Gateways: []MerchantGateway{
+						{ID: "gatewayA"},
+						{ID: "gatewayB", Credentials: "CONFIG_TEST_MERCHANT_API_KEY", PlatformCredentials: true},
+					}},
+				}
+			},
+			expected: []string{
+				"merchants[0] (merchantA): webhook credentials CONFIG_TEST_WEBHOOK_SECRET are not set",
+				"merchants[1]: id is required",
+				"merchants[1] (): webhookCredentials are required",
+				"merchants[1] (): apiKeyHash is used by another merchant",
+				`merchants[2]: duplicate id "merchantA"`,
+				"merchants[2] (merchantA): apiKeyHash must be a hex-encoded SHA-256 hash",
+				`merchants[3] (merchantB): gateways[0]: unknown gateway "gatewayZ"`,
+				"merchants[3] (merchantB): gateways[1] (gatewayA): amounts must be positive, the minimum not above the maximum",
+				`merchants[3] (merchantB): gateways[2]: duplicate gateway "gatewayA"`,
+				"merchants[3] (merchantB): gateways[2] (gatewayA): credentials CONFIG_TEST_MERCHANT_API_KEY are not set",
+				"merchants[4] (merchantC): gateways[0] (gatewayA): credentials are required, unless platformCredentials is set",
+				"merchants[4] (merchantC): gateways[1] (gatewayB): credentials and platformCredentials are exclusive",
+			},
+		},
+		{
+			name: "all gateways disabled",
+			given: func(cfg *Config) {
+				cfg.Gateways = []Gateway{{ID: "gatewayA", Kind: "gatewayA", Enabled: &disabled}}
+			},
+			expected: []string{"gateways: no gateway enabled"},
+		},
+		{
+			name:     "no merchant",
+			given:    func(cfg *Config) {},
+			expected: []string{"merchants: at least one merchant is required, unless auth.disabled is set"},
+		},
+		{
+			name: "authentication disabled with merchants",
+			given: func(cfg *Config) {
+				cfg.Auth.Disabled = true
+				cfg.Merchants = []Merchant{{ID: "merchantA", APIKeyHash: strings.Repeat("a", 64)}}
+			},
+			expected: []string{"auth.disabled: must not be set when merchants are configured"},
+		},
+		{
+			name: "global webhook secret with merchants",
+			given: func(cfg *Config) {
+				cfg.Webhooks.Secret = "secret"
+				cfg.Merchants = []Merchant{{ID: "merchantA", APIKeyHash: strings.Repeat("a", 64)}}
+			},
+			expected: []string{"webhooks.secret: must not be set when merchants are configured"},
+		},
+		{
+			name: "write timeout not longer than the processing timeout",
+			given: func(cfg *Config) {
+				cfg.HTTP.WriteTimeout = Duration(30 * time.Second)
+				cfg.Processing.Timeout = Duration(30 * time.Second)
+			},
+			expected: []string{"http.writeTimeout: must be longer than processing.timeout (30s)"},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			cfg := Default()
+			tc.given(&cfg)
+
+			err := cfg.Validate()
+			for _, expected := range tc.expected {
+				suite.ErrorContains(err, expected)
+			}
+		})
+	}
+}
+
+func (suite *TestConfigSuite) TestCredentials() {
+	suite.T().Setenv("CONFIG_TEST_API_KEY", "secret")
+	suite.T().Setenv("CONFIG_TEST_CALLBACK_SECRET", "callback-secret")
+	suite.T().Setenv("CONFIG_TEST_MERCHANT_API_KEY", "merchant-secret")
+	suite.T().Setenv("CONFIG_TEST_WEBHOOK_SECRET", "webhook-secret")
+
+	cfg := Default()
+	cfg.Gateways[0].Credentials = "CONFIG_TEST_API_KEY"
+	cfg.Gateways[1].CallbackCredentials = "CONFIG_TEST_CALLBACK_SECRET"
+	cfg.Merchants = []Merchant{{ID: "merchantA", APIKeyHash: strings.Repeat("a", 64), WebhookCredentials: "CONFIG_TEST_WEBHOOK_SECRET", Gateways: []MerchantGateway{
+		{ID: "gatewayA", Credentials: "CONFIG_TEST_MERCHANT_API_KEY"},
+		{ID: "gatewayB", PlatformCredentials: true},
+	}}}
+
+	suite.Require().Error(cfg.Validate(), "credentials are resolved before validating")
+
+	cfg.resolveCredentials()
+
+	suite.Require().NoError(cfg.Validate())
+	suite.Equal("secret", cfg.Gateways[0].APIKey)
+	suite.Equal("callback-secret", cfg.Gateways[1].CallbackSecret)
+	suite.Equal("merchant-secret", cfg.Merchants[0].Gateways[0].APIKey)
+	suite.Equal("webhook-secret", cfg.Merchants[0].WebhookSecret)
+	suite.Empty(cfg.Merchants[0].Gateways[1].APIKey, "the API key of the gateway is sent")
+}
+
+func (suite *TestConfigSuite) TestInvalidSources() {
+	path := filepath.Join(suite.T().TempDir(), "config.yaml")
+	suite.Require().NoError(os.WriteFile(path, []byte("http:\n  prot: \"8000\"\n"), 0o600))
+
+	_, err := Load([]string{"-config", path})
+	suite.ErrorContains(err, "field prot not found")
+
+	suite.T().Setenv("GATEWAYB_MAX_RETRIES", "many")
+
+	_, err = Load(nil)
+	suite.ErrorContains(err, "GATEWAYB_MAX_RETRIES")
+}
+
+func (suite *TestConfigSuite) TestWatch() {
+	path := filepath.Join(suite.T().TempDir(), "config.json")
+	write := func(timeout string) {
+		suite.Require().NoError(os.WriteFile(path, []byte(`{"auth": {"disabled": true}, "reload": {"interval": "10ms"}, "processing": {"timeout": "`+timeout+`"}}`), 0o600))
+	}
+	write("10s")
+
+	args := []string{"-config", path}
+	cfg, err := Load(args)
+	suite.Require().NoError(err)
+
+	reloaded := make(chan Config, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	Watch(ctx, args, cfg, func(cfg Config) { reloaded <- cfg })
+
+	// invalid configurations are skipped
+	write("-1s")
+	write("20s")
+
+	select {
+	case cfg := <-reloaded:
+		suite.Equal(Duration(20*time.Second), cfg.Processing.Timeout)
+	case <-time.After(5 * time.Second):
+		suite.Fail("configuration not reloaded")
+	}
+}
+
+func TestTestConfigSuite(t *testing.T) {
+	suite.Run(t, new(TestConfigSuite))
+}
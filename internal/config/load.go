@@ -0,0 +1,310 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration from, by increasing precedence: the defaults, the YAML or JSON file set
+// with -config or CONFIG_FILE, the environment variables and the command-line flags, then validates it
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("payment-service", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("CONFIG_FILE"), "configuration file, YAML or JSON")
+	env := fs.String("env", "", "environment, development enables debug logs")
+	port := fs.String("port", "", "HTTP port")
+	grpcPort := fs.String("grpc-port", "", "gRPC port")
+	callbackURL := fs.String("callback-url", "", "public URL of the /callback endpoint")
+	rulesFile := fs.String("routing-rules", "", "routing rules file")
+
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *path != "" {
+		if err := cfg.load(*path); err != nil {
+			return Config{}, err
+		}
+		cfg.File = *path
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return Config{}, err
+	}
+
+	// flags override everything, when set
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "env":
+			cfg.Env = *env
+		case "port":
+			cfg.HTTP.Port = *port
+		case "grpc-port":
+			cfg.GRPC.Port = *grpcPort
+		case "callback-url":
+			cfg.CallbackURL = *callbackURL
+		case "routing-rules":
+			cfg.Routing.RulesFile = *rulesFile
+		}
+	})
+
+	cfg.resolveCredentials()
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// load overrides the configuration with the file, decoded as JSON or YAML by its extension.
+// The gateways of the file replace the default ones.
+func (c *Config) load(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(b))
+		decoder.KnownFields(true)
+		err = decoder.Decode(c)
+	default:
+		return fmt.Errorf("unsupported configuration file extension %q, expected .json, .yaml or .yml", ext)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to decode configuration file: %w", err)
+	}
+
+	return nil
+}
+
+// applyEnv overrides the configuration with the environment variables set
+func (c *Config) applyEnv() error {
+	var errs []error
+
+	envString("APP_ENV", &c.Env)
+	envString("PORT", &c.HTTP.Port)
+	envString("GRPC_PORT", &c.GRPC.Port)
+	envString("CALLBACK_URL", &c.CallbackURL)
+	envString("WEBHOOK_SECRET", &c.Webhooks.Secret)
+	errs = append(errs, envBool("WEBHOOK_ALLOW_PRIVATE_URLS", &c.Webhooks.AllowPrivateURLs))
+	envString("WEBHOOK_QUEUE_FILE", &c.Webhooks.QueueFile)
+	errs = append(errs, envBool("AUTH_DISABLED", &c.Auth.Disabled))
+	envString("ROUTING_RULES_FILE", &c.Routing.RulesFile)
+	envString("CARD_FINGERPRINT_SECRET", &c.Routing.FingerprintSecret)
+	errs = append(errs, envDuration("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout))
+	errs = append(errs, envDuration("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout))
+	errs = append(errs, envDuration("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout))
+	errs = append(errs, envDuration("PROCESSING_TIMEOUT", &c.Processing.Timeout))
+	errs = append(errs, envDuration("GRPC_SHUTDOWN_TIMEOUT", &c.GRPC.ShutdownTimeout))
+	errs = append(errs, envDuration("CONFIG_RELOAD_INTERVAL", &c.Reload.Interval))
+	errs = append(errs, envDuration("SHUTDOWN_DELAY", &c.Shutdown.Delay))
+	errs = append(errs, envDuration("SHUTDOWN_TIMEOUT", &c.Shutdown.Timeout))
+	envString("TRACING_EXPORTER", &c.Tracing.Exporter)
+	envString("TRACING_ENDPOINT", &c.Tracing.Endpoint)
+	envString("TRACING_SERVICE_NAME", &c.Tracing.ServiceName)
+
+	if value, ok := os.LookupEnv("TRACING_SAMPLE_RATIO"); ok {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO: %w", err))
+		}
+		c.Tracing.SampleRatio = ratio
+	}
+
+	if value, ok := os.LookupEnv("HTTP_MAX_BODY_BYTES"); ok {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_MAX_BODY_BYTES: %w", err))
+		}
+		c.HTTP.MaxBodyBytes = limit
+	}
+
+	if priority, ok := os.LookupEnv("GATEWAY_PRIORITY"); ok {
+		c.Routing.Priority = splitList(priority)
+	}
+
+	if weights, ok := os.LookupEnv("GATEWAY_WEIGHTS"); ok {
+		parsed, err := parseWeights(weights)
+		errs = append(errs, err)
+		c.Routing.Weights = parsed
+	}
+
+	for i := range c.Gateways {
+		errs = append(errs, c.Gateways[i].applyEnv())
+	}
+
+	return errors.Join(errs...)
+}
+
+// applyEnv overrides the gateway settings with the <ID>_ENDPOINT, <ID>_TIMEOUT, <ID>_MAX_RETRIES,
+// <ID>_BREAKER_FAILURES, <ID>_BREAKER_TIMEOUT and <ID>_MAX_CONNS environment variables (e.g. GATEWAYA_TIMEOUT=5s)
+func (g *Gateway) applyEnv() error {
+	prefix := envPrefix(g.ID)
+
+	envString(prefix+"ENDPOINT", &g.Endpoint)
+
+	errs := []error{
+		envDuration(prefix+"TIMEOUT", &g.Timeout),
+		envDuration(prefix+"BREAKER_TIMEOUT", &g.BreakerTimeout),
+	}
+
+	if value, ok := os.LookupEnv(prefix + "MAX_RETRIES"); ok {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sMAX_RETRIES: %w", prefix, err))
+		}
+		g.MaxRetries = &n
+	}
+
+	if value, ok := os.LookupEnv(prefix + "BREAKER_FAILURES"); ok {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sBREAKER_FAILURES: %w", prefix, err))
+		}
+		g.BreakerFailures = uint32(n)
+	}
+
+	if value, ok := os.LookupEnv(prefix + "MAX_CONNS"); ok {
+		n, err := strconv.ParseUint(value, 10, 31)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sMAX_CONNS: %w", prefix, err))
+		}
+		g.MaxConns = int(n)
+	}
+
+	return errors.Join(errs...)
+}
+
+// resolveCredentials reads the API keys and callback secrets of the gateways, the webhook secrets of the merchants
+// and the API keys of their contracts from the environment variables named by their credentials, Validate checks
+// they are set
+func (c *Config) resolveCredentials() {
+	for i := range c.Gateways {
+		gw := &c.Gateways[i]
+		gw.APIKey = credentials(gw.Credentials)
+		gw.CallbackSecret = credentials(gw.CallbackCredentials)
+	}
+
+	for i := range c.Merchants {
+		c.Merchants[i].WebhookSecret = credentials(c.Merchants[i].WebhookCredentials)
+
+		for j := range c.Merchants[i].Gateways {
+			gw := &c.Merchants[i].Gateways[j]
+			gw.APIKey = credentials(gw.Credentials)
+		}
+	}
+}
+
+// credentials returns the value of the environment variable holding the credentials, empty when it has no name
+func credentials(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return os.Getenv(name)
+}
+
+// envPrefix returns the prefix of the environment variables of the gateway, its upper-cased ID with
+// the characters other than letters and digits replaced by underscores
+func envPrefix(id string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(id)) + "_"
+}
+
+// envString sets the string from the environment variable, if set
+func envString(key string, s *string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*s = value
+	}
+}
+
+// envDuration sets the duration from the environment variable, if set
+func envDuration(key string, d *Duration) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// envBool sets the boolean from the environment variable, if set
+func envBool(key string, b *bool) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	*b = parsed
+
+	return nil
+}
+
+// splitList splits a comma separated list, trimming the items and dropping the empty ones
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// parseWeights parses a comma separated list of gateway weights, e.g. gatewayA=90,gatewayB=10
+func parseWeights(value string) ([]GatewayWeight, error) {
+	var weights []GatewayWeight
+
+	for _, pair := range splitList(value) {
+		id, weight, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("GATEWAY_WEIGHTS: expected <gateway>=<weight>, got %q", pair)
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil {
+			return nil, fmt.Errorf("GATEWAY_WEIGHTS: invalid weight for %s: %w", id, err)
+		}
+
+		weights = append(weights, GatewayWeight{GatewayID: strings.TrimSpace(id), Weight: n})
+	}
+
+	return weights, nil
+}
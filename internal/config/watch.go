@@ -0,0 +1,81 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Watch reloads the configuration from args in the background, until ctx is done, when the process receives
+// SIGHUP or, every reload interval, when the configuration or routing rules file has changed, and calls apply
+// with it. Invalid configurations are logged and skipped, the previous one staying in effect.
+func Watch(ctx context.Context, args []string, cfg Config, apply func(Config)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	// the files are not checked without interval
+	var tick <-chan time.Time
+	stopTicker := func() {}
+	if cfg.Reload.Interval > 0 {
+		ticker := time.NewTicker(time.Duration(cfg.Reload.Interval))
+		tick, stopTicker = ticker.C, ticker.Stop
+	}
+
+	versions := fileVersions(cfg)
+
+	go func() {
+		defer signal.Stop(hup)
+		defer stopTicker()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				slog.Info("config: SIGHUP received, reloading")
+			case <-tick:
+				if current := fileVersions(cfg); current == versions {
+					continue
+				}
+				slog.Info("config: file changed, reloading", slog.String("path", cfg.File))
+			}
+
+			loaded, err := Load(args)
+			if err != nil {
+				slog.Error("config: reload failed, keeping the current configuration", slog.Any("error", err))
+				versions = fileVersions(cfg) // retried on the next change only
+				continue
+			}
+
+			cfg = loaded
+			versions = fileVersions(cfg)
+			apply(cfg)
+		}
+	}()
+}
+
+// fileVersion identifies a version of a file by the hash of its content, as the modification time
+// may not change between quick successive writes
+type fileVersion [sha256.Size]byte
+
+// fileVersions returns the versions of the configuration and routing rules files, zero when not set or missing
+func fileVersions(cfg Config) [2]fileVersion {
+	return [2]fileVersion{hashFile(cfg.File), hashFile(cfg.Routing.RulesFile)}
+}
+
+func hashFile(path string) fileVersion {
+	if path == "" {
+		return fileVersion{}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fileVersion{}
+	}
+
+	return sha256.Sum256(b)
+}
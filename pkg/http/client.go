@@ -1,73 +1,235 @@
 package http
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/sony/gobreaker"
 )
 
+var (
+	// ErrRequestRejected is returned when the server answers with a 4xx status other than 429 Too Many Requests.
+	// The request may be the one of a single caller, e.g. with an amount above its limit, so the circuit breaker
+	// shared by all of them does not count it as a failure.
+	ErrRequestRejected = errors.New("request rejected")
+	// ErrCredentialsRejected is returned when the server answers 401 Unauthorized or 403 Forbidden, along with
+	// ErrRequestRejected. The credentials may be the ones of a single caller.
+	ErrCredentialsRejected = errors.New("credentials rejected")
+)
+
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
 // ResilientHTTPClient wraps http.Client and includes Circuit Breaker and Exponential Backoff
 type ResilientHTTPClient struct {
-	client  *http.Client
-	breaker *gobreaker.CircuitBreaker
+	client     *http.Client
+	breaker    *gobreaker.CircuitBreaker
+	maxRetries uint64
+	// retryPolicy decides which failed attempts are retried
+	retryPolicy RetryPolicy
+	// retries counts the attempts retried since the client was created, shared with its copies
+	retries *atomic.Uint64
 }
 
-// NewResilientHTTPClient initializes the HTTP client with circuit breaker and timeout settings
+// ClientConfig configures the timeout, circuit breaker, retries and connection pool of a ResilientHTTPClient
+type ClientConfig struct {
+	Name                    string        // Name of the circuit breaker, used in logs
+	Timeout                 time.Duration // Overall timeout of a single attempt
+	MaxRetries              uint64        // Retries after the first attempt
+	BreakerFailures         uint32        // Consecutive failures tripping the breaker
+	BreakerInterval         time.Duration // Period after which the failure counts are reset while closed
+	BreakerTimeout          time.Duration // How long the breaker stays open before testing
+	BreakerHalfOpenRequests uint32        // Requests allowed while half-open
+	MaxIdleConnsPerHost     int           // Idle connections kept per host
+	MaxConnsPerHost         int           // Connections allowed per host, 0 means no limit
+	IdleConnTimeout         time.Duration // How long idle connections are kept
+}
+
+// DefaultClientConfig returns the default client configuration
+func DefaultClientConfig(name string) ClientConfig {
+	return ClientConfig{
+		Name:                    name,
+		Timeout:                 10 * time.Second, // Set an overall request timeout
+		MaxRetries:              5,
+		BreakerFailures:         3,                // Trip the breaker after 3 failures
+		BreakerInterval:         60 * time.Second, // Reset failure count every 60 seconds
+		BreakerTimeout:          5 * time.Second,  // Circuit stays open for 5 seconds before testing
+		BreakerHalfOpenRequests: 1,                // Allow 1 request in Half-Open state
+		MaxIdleConnsPerHost:     10,
+		IdleConnTimeout:         90 * time.Second,
+	}
+}
+
+// NewResilientHTTPClient initializes the HTTP client with the default circuit breaker and timeout settings
 func NewResilientHTTPClient() *ResilientHTTPClient {
+	return NewResilientHTTPClientWithConfig(DefaultClientConfig("HTTP Client Circuit Breaker"))
+}
+
+// NewResilientHTTPClientWithConfig initializes the HTTP client with its own circuit breaker and connection pool
+func NewResilientHTTPClientWithConfig(cfg ClientConfig) *ResilientHTTPClient {
 	// Configure the circuit breaker
 	cbSettings := gobreaker.Settings{
-		Name:        "HTTP Client Circuit Breaker",
-		MaxRequests: 1,                // Allow 1 request in Half-Open state
-		Interval:    60 * time.Second, // Reset failure count every 60 seconds
-		Timeout:     5 * time.Second,  // Circuit stays open for 5 seconds before testing
+		Name:        cfg.Name,
+		MaxRequests: cfg.BreakerHalfOpenRequests,
+		Interval:    cfg.BreakerInterval,
+		Timeout:     cfg.BreakerTimeout,
 		ReadyToTrip: func(counts gobreaker.Counts) bool {
-			return counts.ConsecutiveFailures >= 3 // Trip the breaker after 3 failures
+			return counts.ConsecutiveFailures >= cfg.BreakerFailures
+		},
+		IsSuccessful: func(err error) bool {
+			// A caller going away or a rejected request are not gateway failures
+			return err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrRequestRejected)
 		},
 		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
-			slog.Info("Circuit breaker state changed", slog.Any("from", from), slog.Any("to", to))
+			slog.Info("Circuit breaker state changed", slog.String("name", name), slog.Any("from", from), slog.Any("to", to))
 		},
 	}
 
+	// Each client gets its own connection pool, so a slow gateway cannot exhaust the connections of another
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+
 	return &ResilientHTTPClient{
-		client:  &http.Client{Timeout: 10 * time.Second}, // Set an overall request timeout
-		breaker: gobreaker.NewCircuitBreaker(cbSettings),
+		client:      &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		breaker:     gobreaker.NewCircuitBreaker(cbSettings),
+		maxRetries:  cfg.MaxRetries,
+		retryPolicy: PaymentRetryPolicy{},
+		retries:     &atomic.Uint64{},
 	}
 }
 
-// Do makes an HTTP request, applies exponential backoff retries, and integrates the circuit breaker
+// WithRetryPolicy returns a copy of the client retrying the failed attempts according to the policy,
+// sharing its circuit breaker, connection pool and retry count
+func (hc *ResilientHTTPClient) WithRetryPolicy(policy RetryPolicy) *ResilientHTTPClient {
+	clone := *hc
+	clone.retryPolicy = policy
+
+	return &clone
+}
+
+// BreakerState holds the state of a circuit breaker (closed, half-open or open) and its counts since the last reset
+type BreakerState struct {
+	State string
+	gobreaker.Counts
+}
+
+// BreakerState returns the current state and counts of the circuit breaker
+func (hc *ResilientHTTPClient) BreakerState() BreakerState {
+	return BreakerState{
+		State:  hc.breaker.State().String(),
+		Counts: hc.breaker.Counts(),
+	}
+}
+
+// Retries returns the number of attempts retried since the client was created
+func (hc *ResilientHTTPClient) Retries() uint64 {
+	return hc.retries.Load()
+}
+
+// CloseIdleConnections closes the idle connections of the connection pool, the ones in use are left open
+func (hc *ResilientHTTPClient) CloseIdleConnections() {
+	hc.client.CloseIdleConnections()
+}
+
+// Do makes an HTTP request, applies exponential backoff retries, and integrates the circuit breaker.
+// Failed attempts are retried according to the RetryPolicy of the client (see WithRetryPolicy),
+// and retries stop as soon as the request context is done. The request body is replayed on every attempt
+// and the responses of failed attempts are drained and closed. The request ID of the request context, if any,
+// is forwarded unless the request already has one.
 func (hc *ResilientHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	req, err := rewindable(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if id := RequestIDFromContext(req.Context()); id != "" && req.Header.Get(HeaderRequestID) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(HeaderRequestID, id)
+	}
+
+	// Use exponential backoff for retrying the request, honoring the gateway's Retry-After and bound to the request context
+	backOff := &retryAfterBackOff{BackOff: backoff.WithMaxRetries(backoff.NewExponentialBackOff(), hc.maxRetries)}
+
 	var resp *http.Response
+	attempts := 0
 
 	// Retry logic wrapped with circuit breaker
 	operation := func() error {
+		attemptReq, err := rewind(req, attempts)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		attempts++
+		if attempts > 1 {
+			hc.retries.Add(1)
+		}
+
+		var failed *http.Response
+
+		// Each attempt is traced, including the ones rejected by an open circuit breaker
+		attemptReq, span := startAttemptSpan(attemptReq, attempts-1)
+
 		// Execute the HTTP request within the circuit breaker context
 		result, err := hc.breaker.Execute(func() (interface{}, error) {
-			var err error
-
-			resp, err = hc.client.Do(req)
+			resp, err := hc.client.Do(attemptReq)
 			if err != nil {
 				return nil, err
 			}
 
 			if resp.StatusCode >= 400 && resp.StatusCode <= 499 { // Treat 4xx HTTP responses as failures
-				return nil, fmt.Errorf("received client error: %d", resp.StatusCode)
+				failed = resp
+				drainAndClose(resp.Body)
+
+				switch resp.StatusCode {
+				case http.StatusTooManyRequests: // The gateway is overloaded, for all the callers
+					return nil, fmt.Errorf("received client error: %d", resp.StatusCode)
+				case http.StatusUnauthorized, http.StatusForbidden:
+					return nil, fmt.Errorf("received client error: %d: %w: %w", resp.StatusCode, ErrRequestRejected, ErrCredentialsRejected)
+				default:
+					return nil, fmt.Errorf("received client error: %d: %w", resp.StatusCode, ErrRequestRejected)
+				}
 			}
 
 			if resp.StatusCode >= 500 { // Treat 5xx HTTP responses as failures
+				failed = resp
+				drainAndClose(resp.Body)
 				return nil, fmt.Errorf("received server error: %d", resp.StatusCode)
 			}
 
 			return resp, nil
 		})
+
+		answered := failed
+		if resp, ok := result.(*http.Response); ok {
+			answered = resp
+		}
+		endAttemptSpan(span, answered, err)
+
 		if err != nil {
+			// Stop retrying once the caller's deadline expires or the caller goes away
+			if req.Context().Err() != nil {
+				return backoff.Permanent(err)
+			}
+
+			retry, after := hc.retryPolicy.Retry(attemptReq, failed, err)
+			if !retry {
+				slog.Debug("http client: attempt not retried", slog.String("url", req.URL.String()), slog.Any("error", err))
+				return backoff.Permanent(err)
+			}
+
+			backOff.after = after
+
 			return err
 		}
 
@@ -76,14 +238,56 @@ func (hc *ResilientHTTPClient) Do(req *http.Request) (*http.Response, error) {
 		return nil
 	}
 
-	// Use exponential backoff for retrying the request
-	backOff := backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 5)
-
 	// Retry the operation with the backoff strategy
-	err := backoff.Retry(operation, backOff)
-	if err != nil {
-		return nil, fmt.Errorf("http request failed after retries: %w", err)
+	if err := backoff.Retry(operation, backoff.WithContext(backOff, req.Context())); err != nil {
+		return nil, fmt.Errorf("http request failed after %d attempt(s): %w", attempts, err)
 	}
 
 	return resp, nil
 }
+
+// rewindable returns the request with GetBody set, buffering bodies that cannot be replayed.
+// Requests built from bytes or strings readers are already rewindable and returned as they are.
+func rewindable(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return req, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer request body: %w", err)
+	}
+
+	req = req.Clone(req.Context())
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
+
+	return req, nil
+}
+
+// rewind returns the request to send for the given attempt, with a fresh copy of the body on retries
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
+	}
+
+	retry := req.Clone(req.Context())
+	retry.Body = body
+
+	return retry, nil
+}
+
+// drainAndClose reads the rest of the body so the connection can be reused, then closes it
+func drainAndClose(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, 64<<10))
+	_ = body.Close()
+}
@@ -0,0 +1,330 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestResilientHTTPClientSuite struct {
+	suite.Suite
+}
+
+func (suite *TestResilientHTTPClientSuite) TestDoContext() {
+	testCases := []struct {
+		name            string
+		timeout         time.Duration
+		cancel          bool
+		expectedErr     error
+		expectedBreaker gobreaker.State
+	}{
+		{
+			name:            "deadline stops retries",
+			timeout:         300 * time.Millisecond,
+			expectedErr:     context.DeadlineExceeded,
+			expectedBreaker: gobreaker.StateClosed,
+		},
+		{
+			name:            "cancellation is not a gateway failure",
+			timeout:         time.Minute,
+			cancel:          true,
+			expectedErr:     context.Canceled,
+			expectedBreaker: gobreaker.StateClosed,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+
+			if tc.cancel {
+				cancel()
+			}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, nil)
+			suite.Require().NoError(err)
+
+			client := NewResilientHTTPClient()
+
+			start := time.Now()
+			_, err = client.Do(req)
+
+			suite.ErrorIs(err, tc.expectedErr)
+			suite.Less(time.Since(start), 2*time.Second)
+			suite.LessOrEqual(calls.Load(), int32(2))
+			suite.Equal(tc.expectedBreaker, client.breaker.State())
+		})
+	}
+}
+
+func (suite *TestResilientHTTPClientSuite) TestDoReplaysBody() {
+	payload := `{"orderId":"123","amount":{"amount":10,"currency":"EUR"}}`
+
+	testCases := []struct {
+		name  string
+		given func() io.Reader
+	}{
+		{
+			name:  "rewindable body",
+			given: func() io.Reader { return bytes.NewBufferString(payload) },
+		},
+		{
+			name:  "streamed body",
+			given: func() io.Reader { return io.MultiReader(strings.NewReader(payload)) },
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var bodies []string
+			var connections atomic.Int32
+
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+
+				mu.Lock()
+				bodies = append(bodies, string(body))
+				attempt := len(bodies)
+				mu.Unlock()
+
+				// fail the first two attempts with a body, which must be drained to reuse the connection
+				if attempt <= 2 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					_, _ = io.WriteString(w, "temporarily unavailable")
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+			}))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					connections.Add(1)
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodPost, server.URL, tc.given())
+			suite.Require().NoError(err)
+
+			client := NewResilientHTTPClient()
+			resp, err := client.Do(req)
+			suite.Require().NoError(err)
+			defer resp.Body.Close()
+
+			suite.Equal(http.StatusOK, resp.StatusCode)
+			suite.Equal([]string{payload, payload, payload}, bodies)
+			suite.Equal(int32(1), connections.Load())
+			suite.Equal(uint64(2), client.Retries())
+		})
+	}
+}
+
+// neverRetry is a retry policy never retrying the failed attempts
+type neverRetry struct{}
+
+func (neverRetry) Retry(*http.Request, *http.Response, error) (bool, time.Duration) {
+	return false, 0
+}
+
+func (suite *TestResilientHTTPClientSuite) TestDoRetryPolicy() {
+	testCases := []struct {
+		name          string
+		status        int
+		retryAfter    string
+		policy        RetryPolicy
+		expectedCalls int32
+		expectedWait  time.Duration
+	}{
+		{
+			name:          "4xx is never retried",
+			status:        http.StatusBadRequest,
+			expectedCalls: 1,
+		},
+		{
+			name:          "503 honors retry after",
+			status:        http.StatusServiceUnavailable,
+			retryAfter:    "1",
+			expectedCalls: 2,
+			expectedWait:  time.Second,
+		},
+		{
+			name:          "client retry policy",
+			status:        http.StatusServiceUnavailable,
+			policy:        neverRetry{},
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) > 1 {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+
+				if tc.retryAfter != "" {
+					w.Header().Set(HeaderRetryAfter, tc.retryAfter)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("{}"))
+			suite.Require().NoError(err)
+
+			client := NewResilientHTTPClient()
+			if tc.policy != nil {
+				client = client.WithRetryPolicy(tc.policy)
+			}
+
+			start := time.Now()
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			suite.Equal(tc.expectedCalls, calls.Load())
+			suite.GreaterOrEqual(time.Since(start), tc.expectedWait)
+		})
+	}
+}
+
+func (suite *TestResilientHTTPClientSuite) TestBreakerPerClient() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := DefaultClientConfig("failing")
+	cfg.MaxRetries = 2
+	cfg.BreakerFailures = 2
+
+	failing := NewResilientHTTPClientWithConfig(cfg)
+	healthy := NewResilientHTTPClientWithConfig(DefaultClientConfig("healthy"))
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	suite.Require().NoError(err)
+
+	_, err = failing.Do(req)
+	suite.Error(err)
+
+	suite.Equal(gobreaker.StateOpen.String(), failing.BreakerState().State)
+	suite.Equal(gobreaker.StateClosed.String(), healthy.BreakerState().State)
+}
+
+func (suite *TestResilientHTTPClientSuite) TestRejectedRequestsNotBreakerFailures() {
+	testCases := []struct {
+		name            string
+		status          int
+		expectedErr     error
+		expectedBreaker string
+	}{
+		{
+			name:            "unprocessable request",
+			status:          http.StatusUnprocessableEntity,
+			expectedErr:     ErrRequestRejected,
+			expectedBreaker: gobreaker.StateClosed.String(),
+		},
+		{
+			name:            "rejected credentials",
+			status:          http.StatusUnauthorized,
+			expectedErr:     ErrCredentialsRejected,
+			expectedBreaker: gobreaker.StateClosed.String(),
+		},
+		{
+			name:            "too many requests",
+			status:          http.StatusTooManyRequests,
+			expectedBreaker: gobreaker.StateOpen.String(),
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			cfg := DefaultClientConfig(tc.name)
+			cfg.MaxRetries = 0
+			cfg.BreakerFailures = 2
+
+			client := NewResilientHTTPClientWithConfig(cfg)
+
+			for range 3 {
+				req, err := http.NewRequest(http.MethodPost, server.URL, nil)
+				suite.Require().NoError(err)
+
+				_, err = client.Do(req)
+				suite.Require().Error(err)
+				if tc.expectedErr != nil {
+					suite.ErrorIs(err, tc.expectedErr)
+				}
+			}
+
+			suite.Equal(tc.expectedBreaker, client.BreakerState().State)
+		})
+	}
+}
+
+func (suite *TestResilientHTTPClientSuite) TestOpenBreakerNotRetried() {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := DefaultClientConfig("open")
+	cfg.MaxRetries = 0
+	cfg.BreakerFailures = 1
+	cfg.BreakerTimeout = time.Minute
+
+	client := NewResilientHTTPClientWithConfig(cfg)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	suite.Require().NoError(err)
+
+	_, err = client.Do(req)
+	suite.Require().Error(err)
+	suite.Require().Equal(gobreaker.StateOpen.String(), client.BreakerState().State)
+
+	// with retries allowed, the rejected request still fails at once so the caller can fail over
+	client.maxRetries = 5
+
+	start := time.Now()
+	_, err = client.Do(req)
+
+	suite.ErrorIs(err, gobreaker.ErrOpenState)
+	suite.True(NotSent(err))
+	suite.Less(time.Since(start), 100*time.Millisecond)
+	suite.Equal(int32(1), calls.Load())
+	suite.Equal(uint64(0), client.Retries())
+}
+
+func TestTestResilientHTTPClientSuite(t *testing.T) {
+	suite.Run(t, new(TestResilientHTTPClientSuite))
+}
@@ -3,12 +3,43 @@ package http
 import (
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
 )
 
+// maxFormMemory is the maximum amount of memory used to parse multipart forms,
+// the remaining parts are stored on disk
+const maxFormMemory = 10 << 20
+
 func Decode(r io.Reader, mimeType string, v any) error {
-	if mimeType == MIMETypeXML {
+	mediaType, params, _ := mime.ParseMediaType(mimeType)
+
+	switch mediaType {
+	case MIMETypeXML:
 		return xml.NewDecoder(r).Decode(v)
+	case MIMETypeForm:
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read form: %w", err)
+		}
+
+		values, err := url.ParseQuery(string(b))
+		if err != nil {
+			return fmt.Errorf("failed to parse form: %w", err)
+		}
+
+		return DecodeForm(values, v)
+	case MIMETypeMultipartForm:
+		form, err := multipart.NewReader(r, params["boundary"]).ReadForm(maxFormMemory)
+		if err != nil {
+			return fmt.Errorf("failed to parse multipart form: %w", err)
+		}
+		defer form.RemoveAll()
+
+		return DecodeForm(form.Value, v)
 	}
 
 	return json.NewDecoder(r).Decode(v)
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FormFieldError is returned when a form value can not be assigned to its field
+type FormFieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FormFieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+func (e *FormFieldError) Unwrap() error {
+	return e.Err
+}
+
+// FormErrors lists every form value that could not be assigned, sorted by field
+type FormErrors []*FormFieldError
+
+func (e FormErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		msgs = append(msgs, fieldErr.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func (e FormErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, fieldErr := range e {
+		errs = append(errs, fieldErr)
+	}
+
+	return errs
+}
+
+// DecodeForm maps flat form values with dotted keys (e.g. "amount.amount", "cardDetails.number")
+// onto the nested struct pointed by v, using the struct json tags to resolve the field names.
+// Keys that do not match any field are ignored, and all the values that can not be assigned
+// are reported together as FormErrors.
+func DecodeForm(values url.Values, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("form decoding requires a non-nil pointer to a struct")
+	}
+
+	var errs FormErrors
+	for key, vals := range values {
+		if len(vals) == 0 {
+			continue
+		}
+
+		field, ok := lookupFormField(rv.Elem(), strings.Split(key, "."))
+		if !ok {
+			continue
+		}
+
+		if err := setFormValue(field, vals[0]); err != nil {
+			errs = append(errs, &FormFieldError{Field: key, Err: err})
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	// map iteration order is random, sort to report the errors consistently
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+
+	return errs
+}
+
+// lookupFormField walks the struct following the path segments,
+// including the fields promoted by embedded structs
+func lookupFormField(v reflect.Value, path []string) (reflect.Value, bool) {
+	for _, name := range path {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+
+		field, ok := structFieldByJSONName(v, name)
+		if !ok {
+			return reflect.Value{}, false
+		}
+
+		v = field
+	}
+
+	return v, v.CanSet()
+}
+
+func structFieldByJSONName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+
+		// embedded structs without a json name have their fields promoted
+		if sf.Anonymous && tag == "" && sf.Type.Kind() == reflect.Struct {
+			if field, ok := structFieldByJSONName(v.Field(i), name); ok {
+				return field, true
+			}
+
+			continue
+		}
+
+		if tag == "" {
+			tag = sf.Name
+		}
+
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+func setFormValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", value)
+		}
+
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package http
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"go-payment-service/pkg/model"
+)
+
+type TestFormSuite struct {
+	suite.Suite
+}
+
+func (suite *TestFormSuite) TestDecodeForm() {
+	testCases := []struct {
+		name           string
+		given          url.Values
+		expected       model.DepositRequest
+		expectedFields []string
+	}{
+		{
+			name: "nested fields",
+			given: url.Values{
+				"amount.amount":          {"10.5"},
+				"amount.currency":        {"EUR"},
+				"cardDetails.number":     {"4111111111111111"},
+				"cardDetails.name":       {"John Doe"},
+				"cardDetails.expiryYear": {"2030"},
+				"gatewayDetails.id":      {"gatewayA"},
+				"unknown.field":          {"ignored"},
+			},
+			expected: model.DepositRequest{
+				BaseRequest: model.BaseRequest{
+					Amount:         model.Money{Amount: 10.5, Currency: "EUR"},
+					CardDetails:    model.CardDetails{Number: "4111111111111111", Name: "John Doe", ExpiryYear: 2030},
+					GatewayDetails: model.GatewayDetails{ID: "gatewayA"},
+				},
+			},
+		},
+		{
+			name: "invalid number",
+			given: url.Values{
+				"amount.amount": {"ten"},
+			},
+			expectedFields: []string{"amount.amount"},
+		},
+		{
+			name: "struct field",
+			given: url.Values{
+				"cardDetails": {"4111111111111111"},
+			},
+			expectedFields: []string{"cardDetails"},
+		},
+		{
+			name: "all invalid fields sorted",
+			given: url.Values{
+				"cardDetails.expiryYear":  {"soon"},
+				"amount.amount":           {"ten"},
+				"cardDetails.expiryMonth": {"may"},
+			},
+			expectedFields: []string{"amount.amount", "cardDetails.expiryMonth", "cardDetails.expiryYear"},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			var req model.DepositRequest
+			err := DecodeForm(tc.given, &req)
+
+			if tc.expectedFields != nil {
+				var formErrs FormErrors
+				suite.Require().ErrorAs(err, &formErrs)
+
+				fields := make([]string, 0, len(formErrs))
+				for _, fieldErr := range formErrs {
+					fields = append(fields, fieldErr.Field)
+				}
+
+				suite.Equal(tc.expectedFields, fields)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Equal(tc.expected, req)
+		})
+	}
+}
+
+func (suite *TestFormSuite) TestDecode() {
+	var multipartBody bytes.Buffer
+	mw := multipart.NewWriter(&multipartBody)
+	suite.Require().NoError(mw.WriteField("amount.currency", "USD"))
+	suite.Require().NoError(mw.WriteField("cardDetails.cvv", "123"))
+	suite.Require().NoError(mw.Close())
+
+	testCases := []struct {
+		name          string
+		given         string
+		givenMIMEType string
+	}{
+		{
+			name:          "urlencoded",
+			given:         "amount.currency=USD&cardDetails.cvv=123",
+			givenMIMEType: MIMETypeForm,
+		},
+		{
+			name:          "multipart",
+			given:         multipartBody.String(),
+			givenMIMEType: mw.FormDataContentType(),
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			var req model.WithdrawalRequest
+			suite.Require().NoError(Decode(strings.NewReader(tc.given), tc.givenMIMEType, &req))
+
+			suite.Equal("USD", req.Amount.Currency)
+			suite.Equal("123", req.CardDetails.CVV)
+		})
+	}
+}
+
+func (suite *TestFormSuite) TestFieldErrors() {
+	req := model.DepositRequest{
+		BaseRequest: model.BaseRequest{
+			Amount:         model.Money{Amount: 10, Currency: "XYZ"},
+			CardDetails:    model.CardDetails{Number: "4111111111111111", Name: "John Doe", ExpiryMonth: 12, ExpiryYear: 2030, CVV: "123"},
+			GatewayDetails: model.GatewayDetails{ID: "gatewayA"},
+		},
+	}
+
+	fieldErrs := FieldErrors(NewValidator().Struct(req))
+
+	suite.Equal([]FieldError{
+		{Field: "amount.currency", Message: "must be a valid ISO 4217 currency code"},
+	}, fieldErrs)
+}
+
+func TestTestFormSuite(t *testing.T) {
+	suite.Run(t, new(TestFormSuite))
+}
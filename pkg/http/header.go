@@ -3,4 +3,30 @@ package http
 const (
 	// HeaderContentType represents the content type header
 	HeaderContentType = "Content-Type"
+	// HeaderAccept represents the accept header
+	HeaderAccept = "Accept"
+	// HeaderCacheControl represents the cache control header
+	HeaderCacheControl = "Cache-Control"
+	// HeaderContentDisposition represents the content disposition header
+	HeaderContentDisposition = "Content-Disposition"
+	// HeaderRetryAfter represents the retry after header
+	HeaderRetryAfter = "Retry-After"
+	// HeaderIdempotencyKey represents the idempotency key header
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderAuthorization represents the authorization header
+	HeaderAuthorization = "Authorization"
+	// HeaderWWWAuthenticate represents the authenticate header
+	HeaderWWWAuthenticate = "WWW-Authenticate"
+	// HeaderRequestID represents the request ID header, correlating the logs of a request across services
+	HeaderRequestID = "X-Request-ID"
+	// HeaderContentTypeOptions represents the content type options header
+	HeaderContentTypeOptions = "X-Content-Type-Options"
+	// HeaderFrameOptions represents the frame options header
+	HeaderFrameOptions = "X-Frame-Options"
+	// HeaderContentSecurityPolicy represents the content security policy header
+	HeaderContentSecurityPolicy = "Content-Security-Policy"
+	// HeaderReferrerPolicy represents the referrer policy header
+	HeaderReferrerPolicy = "Referrer-Policy"
+	// HeaderStrictTransportSecurity represents the strict transport security header
+	HeaderStrictTransportSecurity = "Strict-Transport-Security"
 )
@@ -5,4 +5,14 @@ const (
 	MIMETypeJSON = "application/json"
 	// MIMETypeXML represents XML content type
 	MIMETypeXML = "application/xml"
+	// MIMETypeCSV represents CSV content type
+	MIMETypeCSV = "text/csv"
+	// MIMETypeNDJSON represents newline delimited JSON content type
+	MIMETypeNDJSON = "application/x-ndjson"
+	// MIMETypeEventStream represents server-sent events content type
+	MIMETypeEventStream = "text/event-stream"
+	// MIMETypeForm represents URL encoded form content type
+	MIMETypeForm = "application/x-www-form-urlencoded"
+	// MIMETypeMultipartForm represents multipart form content type
+	MIMETypeMultipartForm = "multipart/form-data"
 )
@@ -0,0 +1,17 @@
+package http
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID, forwarded by ResilientHTTPClient
+// in the X-Request-ID header of the requests made with it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID of the context, empty if it has none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
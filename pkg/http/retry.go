@@ -0,0 +1,112 @@
+package http
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/sony/gobreaker"
+)
+
+// maxRetryAfter is the longest Retry-After delay honored, gateways asking for more are not retried
+const maxRetryAfter = 30 * time.Second
+
+// RetryPolicy decides whether a failed attempt can be retried without risking a duplicate payment
+type RetryPolicy interface {
+	// Retry reports whether the attempt can be retried and the minimum delay before the next attempt.
+	// resp is the failed response, nil when the request failed before a response was received.
+	Retry(req *http.Request, resp *http.Response, err error) (bool, time.Duration)
+}
+
+// PaymentRetryPolicy retries only the attempts the gateway cannot have processed:
+//   - requests that never reached the gateway (connection refused, DNS errors)
+//   - 429 and 503 responses, honoring their Retry-After header
+//
+// Requests rejected by an open or half-open circuit breaker are not retried, so the caller
+// can fail over to another gateway right away instead of waiting for the breaker to close.
+// 4xx responses are never retried. Timeouts, connection resets and other 5xx responses, after which
+// the card may already have been charged, are retried only when the request carries an idempotency key.
+type PaymentRetryPolicy struct {
+	// IdempotencyKeyHeader is the header the gateway deduplicates requests on, empty if it has none
+	IdempotencyKeyHeader string
+}
+
+// Retry implements RetryPolicy
+func (p PaymentRetryPolicy) Retry(req *http.Request, resp *http.Response, err error) (bool, time.Duration) {
+	if resp != nil {
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+			after := retryAfter(resp.Header.Get(HeaderRetryAfter), time.Now())
+			return after <= maxRetryAfter, after
+		case resp.StatusCode >= 400 && resp.StatusCode <= 499:
+			return false, 0
+		default:
+			return p.idempotent(req), 0
+		}
+	}
+
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return false, 0
+	}
+
+	if NotSent(err) {
+		return true, 0
+	}
+
+	return p.idempotent(req), 0
+}
+
+// idempotent reports whether the gateway can deduplicate the request
+func (p PaymentRetryPolicy) idempotent(req *http.Request) bool {
+	return p.IdempotencyKeyHeader != "" && req.Header.Get(p.IdempotencyKeyHeader) != ""
+}
+
+// NotSent reports whether the request failed before reaching the gateway, so it is safe to send it elsewhere
+func NotSent(err error) bool {
+	var dnsErr *net.DNSError
+
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.As(err, &dnsErr) ||
+		errors.Is(err, gobreaker.ErrOpenState) ||
+		errors.Is(err, gobreaker.ErrTooManyRequests)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+
+	return 0
+}
+
+// retryAfterBackOff waits at least the delay requested by the gateway before the next retry
+type retryAfterBackOff struct {
+	backoff.BackOff
+	after time.Duration
+}
+
+// NextBackOff returns the longest of the exponential backoff and the requested delay
+func (b *retryAfterBackOff) NextBackOff() time.Duration {
+	next := b.BackOff.NextBackOff()
+	if next == backoff.Stop {
+		return next
+	}
+
+	after := b.after
+	b.after = 0
+
+	return max(next, after)
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestRetryPolicySuite struct {
+	suite.Suite
+}
+
+// timeoutError is a net.Error reporting a timeout, as returned on read timeouts
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func (suite *TestRetryPolicySuite) TestRetry() {
+	policy := PaymentRetryPolicy{IdempotencyKeyHeader: HeaderIdempotencyKey}
+	timeout := &url.Error{Op: "Post", URL: "http://gateway/process", Err: timeoutError{}}
+
+	testCases := []struct {
+		name           string
+		idempotencyKey string
+		status         int
+		retryAfter     string
+		err            error
+		expected       bool
+		expectedAfter  time.Duration
+	}{
+		{
+			name:     "connection refused",
+			err:      &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED},
+			expected: true,
+		},
+		{
+			name:     "open circuit breaker",
+			err:      gobreaker.ErrOpenState,
+			expected: false,
+		},
+		{
+			name:     "half-open circuit breaker",
+			err:      gobreaker.ErrTooManyRequests,
+			expected: false,
+		},
+		{
+			name:          "503 with retry after",
+			status:        http.StatusServiceUnavailable,
+			retryAfter:    "2",
+			err:           errors.New("received server error: 503"),
+			expected:      true,
+			expectedAfter: 2 * time.Second,
+		},
+		{
+			name:     "429 without retry after",
+			status:   http.StatusTooManyRequests,
+			err:      errors.New("received client error: 429"),
+			expected: true,
+		},
+		{
+			name:          "429 with retry after too long",
+			status:        http.StatusTooManyRequests,
+			retryAfter:    "3600",
+			err:           errors.New("received client error: 429"),
+			expected:      false,
+			expectedAfter: time.Hour,
+		},
+		{
+			name:           "4xx",
+			idempotencyKey: "123",
+			status:         http.StatusBadRequest,
+			err:            errors.New("received client error: 400"),
+			expected:       false,
+		},
+		{
+			name:     "read timeout without idempotency key",
+			err:      timeout,
+			expected: false,
+		},
+		{
+			name:           "read timeout with idempotency key",
+			idempotencyKey: "123",
+			err:            timeout,
+			expected:       true,
+		},
+		{
+			name:     "500 without idempotency key",
+			status:   http.StatusInternalServerError,
+			err:      errors.New("received server error: 500"),
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "http://gateway/process", nil)
+			suite.Require().NoError(err)
+
+			if tc.idempotencyKey != "" {
+				req.Header.Set(HeaderIdempotencyKey, tc.idempotencyKey)
+			}
+
+			var resp *http.Response
+			if tc.status != 0 {
+				resp = &http.Response{StatusCode: tc.status, Header: http.Header{}}
+				if tc.retryAfter != "" {
+					resp.Header.Set(HeaderRetryAfter, tc.retryAfter)
+				}
+			}
+
+			retry, after := policy.Retry(req, resp, fmt.Errorf("attempt failed: %w", tc.err))
+
+			suite.Equal(tc.expected, retry)
+			suite.Equal(tc.expectedAfter, after)
+		})
+	}
+}
+
+func (suite *TestRetryPolicySuite) TestRetryAfter() {
+	now := time.Date(2024, 9, 30, 15, 0, 0, 0, time.UTC)
+
+	suite.Equal(5*time.Second, retryAfter("5", now))
+	suite.Equal(10*time.Second, retryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now))
+	suite.Equal(time.Duration(0), retryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+	suite.Equal(time.Duration(0), retryAfter("soon", now))
+}
+
+func TestTestRetryPolicySuite(t *testing.T) {
+	suite.Run(t, new(TestRetryPolicySuite))
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of the HTTP client, exported by the global tracer provider
+var tracer = otel.Tracer("go-payment-service/pkg/http")
+
+// startAttemptSpan starts the client span of an attempt, the first one being attempt 0, and returns the request
+// carrying it, with its W3C trace context in the headers for the server to continue the trace
+func startAttemptSpan(req *http.Request, attempt int) (*http.Request, trace.Span) {
+	ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", req.URL.Redacted()),
+			attribute.Int("http.request.resend_count", attempt),
+		),
+	)
+
+	// cloned so the headers of the caller's request are left untouched
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	return req, span
+}
+
+// endAttemptSpan records the response status code, if any, and the error of the attempt, then ends its span
+func endAttemptSpan(span trace.Span, resp *http.Response, err error) {
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
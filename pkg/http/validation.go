@@ -0,0 +1,110 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// NewValidator creates a validator that names the fields after their json tags,
+// so validation errors refer to the same field paths clients send
+func NewValidator() *validator.Validate {
+	v := validator.New()
+
+	v.RegisterTagNameFunc(func(sf reflect.StructField) string {
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			return ""
+		}
+
+		return name
+	})
+
+	return v
+}
+
+// FieldError describes an invalid field identified by its dotted json path (e.g. "amount.currency")
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// FieldErrors converts decoding and validation errors into field errors
+func FieldErrors(err error) []FieldError {
+	var formErrs FormErrors
+	if errors.As(err, &formErrs) {
+		fieldErrs := make([]FieldError, 0, len(formErrs))
+		for _, formErr := range formErrs {
+			fieldErrs = append(fieldErrs, FieldError{Field: formErr.Field, Message: formErr.Err.Error()})
+		}
+
+		return fieldErrs
+	}
+
+	var formErr *FormFieldError
+	if errors.As(err, &formErr) {
+		return []FieldError{{Field: formErr.Field, Message: formErr.Err.Error()}}
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+
+	fieldErrs := make([]FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   fieldPath(fe),
+			Message: fieldMessage(fe),
+		})
+	}
+
+	return fieldErrs
+}
+
+// fieldPath removes the root struct and the embedded structs (which have no json name) from the namespace
+func fieldPath(fe validator.FieldError) string {
+	segments := strings.Split(fe.Namespace(), ".")
+
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments[1:] {
+		if r := []rune(segment); len(r) > 0 && unicode.IsUpper(r[0]) {
+			continue
+		}
+
+		path = append(path, segment)
+	}
+
+	return strings.Join(path, ".")
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "iso4217":
+		return "must be a valid ISO 4217 currency code"
+	case "credit_card":
+		return "must be a valid card number"
+	}
+
+	return fmt.Sprintf("failed on the '%s' validation", fe.Tag())
+}
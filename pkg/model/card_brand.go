@@ -0,0 +1,49 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CardBrand represents the card network detected from the card number
+type CardBrand string
+
+const (
+	Visa         CardBrand = "visa"
+	Mastercard   CardBrand = "mastercard"
+	Amex         CardBrand = "amex"
+	Discover     CardBrand = "discover"
+	UnknownBrand CardBrand = "unknown"
+)
+
+// DetectCardBrand detects the card brand from the leading digits of the card number
+func DetectCardBrand(number string) CardBrand {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(number)
+
+	switch {
+	case hasPrefixInRange(digits, 2, 34, 34), hasPrefixInRange(digits, 2, 37, 37):
+		return Amex
+	case strings.HasPrefix(digits, "4"):
+		return Visa
+	case hasPrefixInRange(digits, 2, 51, 55), hasPrefixInRange(digits, 4, 2221, 2720):
+		return Mastercard
+	case strings.HasPrefix(digits, "6011"), strings.HasPrefix(digits, "65"), hasPrefixInRange(digits, 3, 644, 649):
+		return Discover
+	default:
+		return UnknownBrand
+	}
+}
+
+// hasPrefixInRange reports whether the first n digits of the number are within [from, to]
+func hasPrefixInRange(digits string, n, from, to int) bool {
+	if len(digits) < n {
+		return false
+	}
+
+	prefix, err := strconv.Atoi(digits[:n])
+	if err != nil {
+		return false
+	}
+
+	return prefix >= from && prefix <= to
+}
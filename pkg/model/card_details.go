@@ -1,5 +1,12 @@
 package model
 
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
 // CardDetails holds information about the card used in the transaction
 type CardDetails struct {
 	Name        string `json:"name" xml:"name" validate:"required"`
@@ -9,3 +16,32 @@ type CardDetails struct {
 	ExpiryYear  int    `json:"expiryYear" xml:"expiryYear" validate:"min=2021,max=2040"`
 	CVV         string `json:"cvv" xml:"cvv" validate:"min=3,max=4"`
 }
+
+// Masked returns a copy of the card details safe to be exposed, with the number
+// masked except for its first six and last four digits and without the CVV
+func (c CardDetails) Masked() CardDetails {
+	c.Number = MaskCardNumber(c.Number)
+	c.CVV = ""
+
+	return c
+}
+
+// MaskCardNumber masks all digits of a card number except for its BIN (first six) and last four digits
+func MaskCardNumber(number string) string {
+	if len(number) <= 10 {
+		return strings.Repeat("*", len(number))
+	}
+
+	return number[:6] + strings.Repeat("*", len(number)-10) + number[len(number)-4:]
+}
+
+// Fingerprint returns a stable identifier of the card, the HMAC-SHA256 of its number keyed with the secret,
+// so the card numbers can't be recovered by hashing all the possible ones without knowing it
+func (c CardDetails) Fingerprint(secret []byte) string {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(c.Number)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(digits))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,37 @@
+package model
+
+// GatewayStatus holds the health of a registered payment gateway
+type GatewayStatus struct {
+	ID             string              `json:"id" xml:"id"`
+	CircuitBreaker CircuitBreakerState `json:"circuitBreaker" xml:"circuitBreaker"`
+	Stats          GatewayStats        `json:"stats" xml:"stats"`
+	Capabilities   GatewayCapabilities `json:"capabilities" xml:"capabilities"`
+}
+
+// GatewayCapabilities declares what a gateway supports, empty lists and a zero maximum amount mean no restriction
+type GatewayCapabilities struct {
+	Currencies []string          `json:"currencies,omitempty" xml:"currency,omitempty"`
+	Types      []TransactionType `json:"types,omitempty" xml:"type,omitempty"`
+	MinAmount  float64           `json:"minAmount,omitempty" xml:"minAmount,omitempty"` // Inclusive
+	MaxAmount  float64           `json:"maxAmount,omitempty" xml:"maxAmount,omitempty"` // Inclusive
+	CardBrands []CardBrand       `json:"cardBrands,omitempty" xml:"cardBrand,omitempty"`
+}
+
+// GatewayStats holds the outcomes of the transactions processed by a gateway since the service started
+type GatewayStats struct {
+	Succeeded uint64 `json:"succeeded" xml:"succeeded"`
+	Failed    uint64 `json:"failed" xml:"failed"`
+	// SuccessRate is the share of succeeded transactions among the completed ones, between 0 and 1
+	SuccessRate float64 `json:"successRate" xml:"successRate"`
+}
+
+// CircuitBreakerState holds the state of a gateway circuit breaker (closed, half-open or open)
+// and its counts since the last reset
+type CircuitBreakerState struct {
+	State                string `json:"state" xml:"state"`
+	Requests             uint32 `json:"requests" xml:"requests"`
+	TotalSuccesses       uint32 `json:"totalSuccesses" xml:"totalSuccesses"`
+	TotalFailures        uint32 `json:"totalFailures" xml:"totalFailures"`
+	ConsecutiveSuccesses uint32 `json:"consecutiveSuccesses" xml:"consecutiveSuccesses"`
+	ConsecutiveFailures  uint32 `json:"consecutiveFailures" xml:"consecutiveFailures"`
+}
@@ -2,7 +2,8 @@ package model
 
 // GatewayDetails holds information about the payment gateway used
 type GatewayDetails struct {
-	ID          string `json:"id" xml:"id" validate:"required"`
+	ID          string `json:"id" xml:"id"` // Gateway to use, chosen by priority and health when empty
 	Name        string `json:"name" xml:"name"`
-	CallbackURL string `json:"callbackUrl" xml:"callbackUrl"` // URL where the payment gateway sends transaction updates
+	CallbackURL string `json:"callbackUrl" xml:"callbackUrl"` // URL where the merchant receives the transaction status webhooks
+	Fallback    bool   `json:"fallback" xml:"fallback"`       // Allow failing over to other gateways when the chosen one is down
 }
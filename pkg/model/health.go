@@ -0,0 +1,23 @@
+package model
+
+// HealthState represents the outcome of a health check
+type HealthState string
+
+const (
+	HealthOK   HealthState = "ok"
+	HealthFail HealthState = "fail"
+)
+
+// HealthStatus holds the outcome of the health checks of the service, failing when any of them fails
+type HealthStatus struct {
+	Status HealthState   `json:"status" xml:"status"`
+	Checks []HealthCheck `json:"checks,omitempty" xml:"check,omitempty"`
+}
+
+// HealthCheck holds the outcome of a single health check
+type HealthCheck struct {
+	Name     string      `json:"name" xml:"name"`
+	Status   HealthState `json:"status" xml:"status"`
+	Error    string      `json:"error,omitempty" xml:"error,omitempty"`
+	Duration string      `json:"duration" xml:"duration"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// Merchant represents a client of the API, owning the transactions it creates
+type Merchant struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Admin allows the merchant to call the admin routes
+	Admin bool `json:"admin,omitempty"`
+}
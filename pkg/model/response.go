@@ -12,9 +12,12 @@ type WithdrawalResponse struct {
 
 // ErrorResponse represents a standardized error response
 type ErrorResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Code    int          `json:"code"`
+	Message string       `json:"message"`
+	Details string       `json:"details,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+	// RequestID identifies the request in the logs of the service
+	RequestID string `json:"requestId,omitempty"`
 }
 
 // GatewayResponse represents the response from a payment gateway
@@ -25,3 +28,9 @@ type GatewayResponse struct {
 	Status        TransactionStatus `json:"status" xml:"status"`
 	ProcessedAt   time.Time         `json:"processedAt" xml:"processedAt"`
 }
+
+// FieldError describes an invalid field of a request
+type FieldError struct {
+	Field   string `json:"field" xml:"field"`
+	Message string `json:"message" xml:"message"`
+}
@@ -0,0 +1,69 @@
+package model
+
+import "time"
+
+// RoutingOutcome represents the outcome of sending a transaction to a gateway
+type RoutingOutcome string
+
+const (
+	// RoutingProcessed means the gateway accepted the transaction
+	RoutingProcessed RoutingOutcome = "processed"
+	// RoutingFailedOver means the gateway could not be reached and the next one was tried
+	RoutingFailedOver RoutingOutcome = "failed_over"
+	// RoutingFailed means the gateway rejected the transaction or may have processed it, so routing stopped
+	RoutingFailed RoutingOutcome = "failed"
+)
+
+// RoutingAttempt records a routing decision taken for a transaction
+type RoutingAttempt struct {
+	GatewayID   string         `json:"gatewayId" xml:"gatewayId"`
+	Outcome     RoutingOutcome `json:"outcome" xml:"outcome"`
+	Reason      string         `json:"reason,omitempty" xml:"reason,omitempty"`
+	Rule        string         `json:"rule,omitempty" xml:"rule,omitempty"` // Routing rule that selected the gateway, if any
+	AttemptedAt time.Time      `json:"attemptedAt" xml:"attemptedAt"`
+}
+
+// RoutingAttributes holds the transaction attributes the routing rules are evaluated against
+type RoutingAttributes struct {
+	Type       TransactionType `json:"type" xml:"type"`
+	Currency   string          `json:"currency" xml:"currency"`
+	Amount     float64         `json:"amount" xml:"amount"`
+	CardBrand  CardBrand       `json:"cardBrand" xml:"cardBrand"`
+	BINCountry string          `json:"binCountry,omitempty" xml:"binCountry,omitempty"`
+}
+
+// RoutingDecision explains how the gateways of a transaction are chosen
+type RoutingDecision struct {
+	// Gateways to try, in order
+	Gateways   []string          `json:"gateways" xml:"gateways"`
+	Rule       string            `json:"rule,omitempty" xml:"rule,omitempty"`
+	Reason     string            `json:"reason" xml:"reason"`
+	Attributes RoutingAttributes `json:"attributes" xml:"attributes"`
+	// Gateways left out because they do not support the transaction
+	Excluded []GatewayExclusion `json:"excluded,omitempty" xml:"excluded,omitempty"`
+}
+
+// GatewayExclusion explains why a gateway was left out of the routing
+type GatewayExclusion struct {
+	GatewayID string `json:"gatewayId" xml:"gatewayId"`
+	Reason    string `json:"reason" xml:"reason"`
+}
+
+// RoutingDryRunRequest represents a sample transaction to explain the routing of
+type RoutingDryRunRequest struct {
+	Type           TransactionType `json:"type" xml:"type" validate:"oneof=deposit withdrawal"`
+	Amount         Money           `json:"amount" xml:"amount" validate:"required"`
+	CardNumber     string          `json:"cardNumber" xml:"cardNumber" validate:"required"`
+	GatewayDetails GatewayDetails  `json:"gatewayDetails" xml:"gatewayDetails"`
+}
+
+// GatewayWeight represents the percentage of the traffic sent to a gateway
+type GatewayWeight struct {
+	GatewayID string `json:"gatewayId" xml:"gatewayId" validate:"required"`
+	Weight    int    `json:"weight" xml:"weight" validate:"gte=0,lte=100"`
+}
+
+// RoutingWeights represents the weighted split of the traffic between gateways, weights adding up to 100
+type RoutingWeights struct {
+	Weights []GatewayWeight `json:"weights" xml:"weight" validate:"dive"`
+}
@@ -15,12 +15,21 @@ const (
 // Transaction represents a financial transaction (deposit or withdrawal)
 type Transaction struct {
 	ID             string            `json:"id"`
+	MerchantID     string            `json:"merchantId,omitempty"` // Merchant who created the transaction, none when the API is not authenticated
 	Amount         Money             `json:"amount"`
 	CardDetails    CardDetails       `json:"cardDetails"`
 	GatewayDetails GatewayDetails    `json:"gatewayDetails"`
 	Type           TransactionType   `json:"type"`
 	Status         TransactionStatus `json:"status"`
 	ExternalID     string            `json:"externalId"`
+	Routing        []RoutingAttempt  `json:"routing,omitempty"`
 	CreatedAt      time.Time         `json:"createdAt"`
 	UpdatedAt      time.Time         `json:"updatedAt"`
 }
+
+// Masked returns a copy of the transaction with its card details masked
+func (t Transaction) Masked() Transaction {
+	t.CardDetails = t.CardDetails.Masked()
+
+	return t
+}
@@ -9,3 +9,18 @@ const (
 	Processing TransactionStatus = "processing"
 	Succeeded  TransactionStatus = "succeeded"
 )
+
+// IsTerminal reports whether the status is final and will not change anymore
+func (s TransactionStatus) IsTerminal() bool {
+	return s == Succeeded || s == Failed
+}
+
+// IsValid reports whether the status is one of the known statuses
+func (s TransactionStatus) IsValid() bool {
+	switch s {
+	case Failed, Pending, Processing, Succeeded:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// WebhookEventType represents the type of a webhook event
+type WebhookEventType string
+
+const (
+	TransactionStatusChanged WebhookEventType = "transaction.status_changed"
+)
+
+// WebhookEvent is the normalized notification sent to merchants, regardless of the payment gateway
+type WebhookEvent struct {
+	ID              string            `json:"id"`
+	Type            WebhookEventType  `json:"type"`
+	TransactionID   string            `json:"transactionId"`
+	TransactionType TransactionType   `json:"transactionType"`
+	Status          TransactionStatus `json:"status"`
+	Amount          Money             `json:"amount"`
+	GatewayID       string            `json:"gatewayId"`
+	OccurredAt      time.Time         `json:"occurredAt"`
+}
+
+// WebhookDeliveryStatus represents the current status of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliverySucceeded WebhookDeliveryStatus = "delivered"
+	DeliveryDead      WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery tracks the delivery of a webhook event to a merchant URL
+type WebhookDelivery struct {
+	ID            string                `json:"id"`
+	MerchantID    string                `json:"merchantId,omitempty"` // Merchant who created the transaction
+	URL           string                `json:"url"`
+	Event         WebhookEvent          `json:"event"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int                   `json:"attempts"`
+	LastError     string                `json:"lastError,omitempty"`
+	NextAttemptAt time.Time             `json:"nextAttemptAt"`
+	CreatedAt     time.Time             `json:"createdAt"`
+	UpdatedAt     time.Time             `json:"updatedAt"`
+	DeliveredAt   time.Time             `json:"deliveredAt,omitempty"`
+}
@@ -0,0 +1,1069 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: payment/v1/transaction.proto
+
+package paymentv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// TransactionType represents the type of transaction
+type TransactionType int32
+
+const (
+	TransactionType_TRANSACTION_TYPE_UNSPECIFIED TransactionType = 0
+	TransactionType_TRANSACTION_TYPE_DEPOSIT     TransactionType = 1
+	TransactionType_TRANSACTION_TYPE_WITHDRAWAL  TransactionType = 2
+)
+
+// Enum value maps for TransactionType.
+var (
+	TransactionType_name = map[int32]string{
+		0: "TRANSACTION_TYPE_UNSPECIFIED",
+		1: "TRANSACTION_TYPE_DEPOSIT",
+		2: "TRANSACTION_TYPE_WITHDRAWAL",
+	}
+	TransactionType_value = map[string]int32{
+		"TRANSACTION_TYPE_UNSPECIFIED": 0,
+		"TRANSACTION_TYPE_DEPOSIT":     1,
+		"TRANSACTION_TYPE_WITHDRAWAL":  2,
+	}
+)
+
+func (x TransactionType) Enum() *TransactionType {
+	p := new(TransactionType)
+	*p = x
+	return p
+}
+
+func (x TransactionType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TransactionType) Descriptor() protoreflect.EnumDescriptor {
+	return file_payment_v1_transaction_proto_enumTypes[0].Descriptor()
+}
+
+func (TransactionType) Type() protoreflect.EnumType {
+	return &file_payment_v1_transaction_proto_enumTypes[0]
+}
+
+func (x TransactionType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TransactionType.Descriptor instead.
+func (TransactionType) EnumDescriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{0}
+}
+
+// TransactionStatus represents the current status of a transaction
+type TransactionStatus int32
+
+const (
+	TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED TransactionStatus = 0
+	TransactionStatus_TRANSACTION_STATUS_PENDING     TransactionStatus = 1
+	TransactionStatus_TRANSACTION_STATUS_PROCESSING  TransactionStatus = 2
+	TransactionStatus_TRANSACTION_STATUS_SUCCEEDED   TransactionStatus = 3
+	TransactionStatus_TRANSACTION_STATUS_FAILED      TransactionStatus = 4
+)
+
+// Enum value maps for TransactionStatus.
+var (
+	TransactionStatus_name = map[int32]string{
+		0: "TRANSACTION_STATUS_UNSPECIFIED",
+		1: "TRANSACTION_STATUS_PENDING",
+		2: "TRANSACTION_STATUS_PROCESSING",
+		3: "TRANSACTION_STATUS_SUCCEEDED",
+		4: "TRANSACTION_STATUS_FAILED",
+	}
+	TransactionStatus_value = map[string]int32{
+		"TRANSACTION_STATUS_UNSPECIFIED": 0,
+		"TRANSACTION_STATUS_PENDING":     1,
+		"TRANSACTION_STATUS_PROCESSING":  2,
+		"TRANSACTION_STATUS_SUCCEEDED":   3,
+		"TRANSACTION_STATUS_FAILED":      4,
+	}
+)
+
+func (x TransactionStatus) Enum() *TransactionStatus {
+	p := new(TransactionStatus)
+	*p = x
+	return p
+}
+
+func (x TransactionStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TransactionStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_payment_v1_transaction_proto_enumTypes[1].Descriptor()
+}
+
+func (TransactionStatus) Type() protoreflect.EnumType {
+	return &file_payment_v1_transaction_proto_enumTypes[1]
+}
+
+func (x TransactionStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TransactionStatus.Descriptor instead.
+func (TransactionStatus) EnumDescriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{1}
+}
+
+type Money struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Amount        float64                `protobuf:"fixed64,1,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency      string                 `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Money) Reset() {
+	*x = Money{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Money) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Money) ProtoMessage() {}
+
+func (x *Money) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Money.ProtoReflect.Descriptor instead.
+func (*Money) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Money) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Money) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+type CardDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Number        string                 `protobuf:"bytes,2,opt,name=number,proto3" json:"number,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	ExpiryMonth   int32                  `protobuf:"varint,4,opt,name=expiry_month,json=expiryMonth,proto3" json:"expiry_month,omitempty"`
+	ExpiryYear    int32                  `protobuf:"varint,5,opt,name=expiry_year,json=expiryYear,proto3" json:"expiry_year,omitempty"`
+	Cvv           string                 `protobuf:"bytes,6,opt,name=cvv,proto3" json:"cvv,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CardDetails) Reset() {
+	*x = CardDetails{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CardDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CardDetails) ProtoMessage() {}
+
+func (x *CardDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CardDetails.ProtoReflect.Descriptor instead.
+func (*CardDetails) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CardDetails) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CardDetails) GetNumber() string {
+	if x != nil {
+		return x.Number
+	}
+	return ""
+}
+
+func (x *CardDetails) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *CardDetails) GetExpiryMonth() int32 {
+	if x != nil {
+		return x.ExpiryMonth
+	}
+	return 0
+}
+
+func (x *CardDetails) GetExpiryYear() int32 {
+	if x != nil {
+		return x.ExpiryYear
+	}
+	return 0
+}
+
+func (x *CardDetails) GetCvv() string {
+	if x != nil {
+		return x.Cvv
+	}
+	return ""
+}
+
+type GatewayDetails struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Gateway to use, chosen by priority and health when empty.
+	Id          string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name        string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	CallbackUrl string `protobuf:"bytes,3,opt,name=callback_url,json=callbackUrl,proto3" json:"callback_url,omitempty"`
+	// Allow failing over to other gateways when the chosen one is down.
+	Fallback      bool `protobuf:"varint,4,opt,name=fallback,proto3" json:"fallback,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GatewayDetails) Reset() {
+	*x = GatewayDetails{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GatewayDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GatewayDetails) ProtoMessage() {}
+
+func (x *GatewayDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GatewayDetails.ProtoReflect.Descriptor instead.
+func (*GatewayDetails) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GatewayDetails) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GatewayDetails) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GatewayDetails) GetCallbackUrl() string {
+	if x != nil {
+		return x.CallbackUrl
+	}
+	return ""
+}
+
+func (x *GatewayDetails) GetFallback() bool {
+	if x != nil {
+		return x.Fallback
+	}
+	return false
+}
+
+// A routing decision taken for a transaction.
+type RoutingAttempt struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	GatewayId string                 `protobuf:"bytes,1,opt,name=gateway_id,json=gatewayId,proto3" json:"gateway_id,omitempty"`
+	// One of processed, failed_over or failed.
+	Outcome       string                 `protobuf:"bytes,2,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	AttemptedAt   *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=attempted_at,json=attemptedAt,proto3" json:"attempted_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoutingAttempt) Reset() {
+	*x = RoutingAttempt{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoutingAttempt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoutingAttempt) ProtoMessage() {}
+
+func (x *RoutingAttempt) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoutingAttempt.ProtoReflect.Descriptor instead.
+func (*RoutingAttempt) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RoutingAttempt) GetGatewayId() string {
+	if x != nil {
+		return x.GatewayId
+	}
+	return ""
+}
+
+func (x *RoutingAttempt) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+func (x *RoutingAttempt) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *RoutingAttempt) GetAttemptedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AttemptedAt
+	}
+	return nil
+}
+
+type DepositRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Amount         *Money                 `protobuf:"bytes,1,opt,name=amount,proto3" json:"amount,omitempty"`
+	CardDetails    *CardDetails           `protobuf:"bytes,2,opt,name=card_details,json=cardDetails,proto3" json:"card_details,omitempty"`
+	GatewayDetails *GatewayDetails        `protobuf:"bytes,3,opt,name=gateway_details,json=gatewayDetails,proto3" json:"gateway_details,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DepositRequest) Reset() {
+	*x = DepositRequest{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DepositRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DepositRequest) ProtoMessage() {}
+
+func (x *DepositRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DepositRequest.ProtoReflect.Descriptor instead.
+func (*DepositRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DepositRequest) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *DepositRequest) GetCardDetails() *CardDetails {
+	if x != nil {
+		return x.CardDetails
+	}
+	return nil
+}
+
+func (x *DepositRequest) GetGatewayDetails() *GatewayDetails {
+	if x != nil {
+		return x.GatewayDetails
+	}
+	return nil
+}
+
+type WithdrawalRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Amount         *Money                 `protobuf:"bytes,1,opt,name=amount,proto3" json:"amount,omitempty"`
+	CardDetails    *CardDetails           `protobuf:"bytes,2,opt,name=card_details,json=cardDetails,proto3" json:"card_details,omitempty"`
+	GatewayDetails *GatewayDetails        `protobuf:"bytes,3,opt,name=gateway_details,json=gatewayDetails,proto3" json:"gateway_details,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *WithdrawalRequest) Reset() {
+	*x = WithdrawalRequest{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WithdrawalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithdrawalRequest) ProtoMessage() {}
+
+func (x *WithdrawalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithdrawalRequest.ProtoReflect.Descriptor instead.
+func (*WithdrawalRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WithdrawalRequest) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *WithdrawalRequest) GetCardDetails() *CardDetails {
+	if x != nil {
+		return x.CardDetails
+	}
+	return nil
+}
+
+func (x *WithdrawalRequest) GetGatewayDetails() *GatewayDetails {
+	if x != nil {
+		return x.GatewayDetails
+	}
+	return nil
+}
+
+type TransactionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransactionId string                 `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Status        TransactionStatus      `protobuf:"varint,3,opt,name=status,proto3,enum=payment.v1.TransactionStatus" json:"status,omitempty"`
+	ProcessedAt   *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=processed_at,json=processedAt,proto3" json:"processed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionResponse) Reset() {
+	*x = TransactionResponse{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionResponse) ProtoMessage() {}
+
+func (x *TransactionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionResponse.ProtoReflect.Descriptor instead.
+func (*TransactionResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TransactionResponse) GetTransactionId() string {
+	if x != nil {
+		return x.TransactionId
+	}
+	return ""
+}
+
+func (x *TransactionResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *TransactionResponse) GetStatus() TransactionStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED
+}
+
+func (x *TransactionResponse) GetProcessedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ProcessedAt
+	}
+	return nil
+}
+
+type GetByIDRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetByIDRequest) Reset() {
+	*x = GetByIDRequest{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetByIDRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetByIDRequest) ProtoMessage() {}
+
+func (x *GetByIDRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetByIDRequest.ProtoReflect.Descriptor instead.
+func (*GetByIDRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetByIDRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type UpdateStatusRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ID of the transaction on the payment gateway side
+	TransactionId string            `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	Status        TransactionStatus `protobuf:"varint,2,opt,name=status,proto3,enum=payment.v1.TransactionStatus" json:"status,omitempty"`
+	Details       string            `protobuf:"bytes,3,opt,name=details,proto3" json:"details,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateStatusRequest) Reset() {
+	*x = UpdateStatusRequest{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateStatusRequest) ProtoMessage() {}
+
+func (x *UpdateStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateStatusRequest.ProtoReflect.Descriptor instead.
+func (*UpdateStatusRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UpdateStatusRequest) GetTransactionId() string {
+	if x != nil {
+		return x.TransactionId
+	}
+	return ""
+}
+
+func (x *UpdateStatusRequest) GetStatus() TransactionStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED
+}
+
+func (x *UpdateStatusRequest) GetDetails() string {
+	if x != nil {
+		return x.Details
+	}
+	return ""
+}
+
+type UpdateStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateStatusResponse) Reset() {
+	*x = UpdateStatusResponse{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateStatusResponse) ProtoMessage() {}
+
+func (x *UpdateStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateStatusResponse.ProtoReflect.Descriptor instead.
+func (*UpdateStatusResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{9}
+}
+
+type WatchTransactionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchTransactionRequest) Reset() {
+	*x = WatchTransactionRequest{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchTransactionRequest) ProtoMessage() {}
+
+func (x *WatchTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchTransactionRequest.ProtoReflect.Descriptor instead.
+func (*WatchTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *WatchTransactionRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Transaction struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Amount         *Money                 `protobuf:"bytes,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	CardDetails    *CardDetails           `protobuf:"bytes,3,opt,name=card_details,json=cardDetails,proto3" json:"card_details,omitempty"`
+	GatewayDetails *GatewayDetails        `protobuf:"bytes,4,opt,name=gateway_details,json=gatewayDetails,proto3" json:"gateway_details,omitempty"`
+	Type           TransactionType        `protobuf:"varint,5,opt,name=type,proto3,enum=payment.v1.TransactionType" json:"type,omitempty"`
+	Status         TransactionStatus      `protobuf:"varint,6,opt,name=status,proto3,enum=payment.v1.TransactionStatus" json:"status,omitempty"`
+	ExternalId     string                 `protobuf:"bytes,7,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Routing        []*RoutingAttempt      `protobuf:"bytes,10,rep,name=routing,proto3" json:"routing,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_payment_v1_transaction_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_transaction_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_payment_v1_transaction_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Transaction) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Transaction) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *Transaction) GetCardDetails() *CardDetails {
+	if x != nil {
+		return x.CardDetails
+	}
+	return nil
+}
+
+func (x *Transaction) GetGatewayDetails() *GatewayDetails {
+	if x != nil {
+		return x.GatewayDetails
+	}
+	return nil
+}
+
+func (x *Transaction) GetType() TransactionType {
+	if x != nil {
+		return x.Type
+	}
+	return TransactionType_TRANSACTION_TYPE_UNSPECIFIED
+}
+
+func (x *Transaction) GetStatus() TransactionStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED
+}
+
+func (x *Transaction) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
+func (x *Transaction) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Transaction) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Transaction) GetRouting() []*RoutingAttempt {
+	if x != nil {
+		return x.Routing
+	}
+	return nil
+}
+
+var File_payment_v1_transaction_proto protoreflect.FileDescriptor
+
+const file_payment_v1_transaction_proto_rawDesc = "" +
+	"\n" +
+	"\x1cpayment/v1/transaction.proto\x12\n" +
+	"payment.v1\x1a\x1fgoogle/protobuf/timestamp.proto\";\n" +
+	"\x05Money\x12\x16\n" +
+	"\x06amount\x18\x01 \x01(\x01R\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\"\xa3\x01\n" +
+	"\vCardDetails\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06number\x18\x02 \x01(\tR\x06number\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12!\n" +
+	"\fexpiry_month\x18\x04 \x01(\x05R\vexpiryMonth\x12\x1f\n" +
+	"\vexpiry_year\x18\x05 \x01(\x05R\n" +
+	"expiryYear\x12\x10\n" +
+	"\x03cvv\x18\x06 \x01(\tR\x03cvv\"s\n" +
+	"\x0eGatewayDetails\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12!\n" +
+	"\fcallback_url\x18\x03 \x01(\tR\vcallbackUrl\x12\x1a\n" +
+	"\bfallback\x18\x04 \x01(\bR\bfallback\"\xa0\x01\n" +
+	"\x0eRoutingAttempt\x12\x1d\n" +
+	"\n" +
+	"gateway_id\x18\x01 \x01(\tR\tgatewayId\x12\x18\n" +
+	"\aoutcome\x18\x02 \x01(\tR\aoutcome\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12=\n" +
+	"\fattempted_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\vattemptedAt\"\xbc\x01\n" +
+	"\x0eDepositRequest\x12)\n" +
+	"\x06amount\x18\x01 \x01(\v2\x11.payment.v1.MoneyR\x06amount\x12:\n" +
+	"\fcard_details\x18\x02 \x01(\v2\x17.payment.v1.CardDetailsR\vcardDetails\x12C\n" +
+	"\x0fgateway_details\x18\x03 \x01(\v2\x1a.payment.v1.GatewayDetailsR\x0egatewayDetails\"\xbf\x01\n" +
+	"\x11WithdrawalRequest\x12)\n" +
+	"\x06amount\x18\x01 \x01(\v2\x11.payment.v1.MoneyR\x06amount\x12:\n" +
+	"\fcard_details\x18\x02 \x01(\v2\x17.payment.v1.CardDetailsR\vcardDetails\x12C\n" +
+	"\x0fgateway_details\x18\x03 \x01(\v2\x1a.payment.v1.GatewayDetailsR\x0egatewayDetails\"\xcc\x01\n" +
+	"\x13TransactionResponse\x12%\n" +
+	"\x0etransaction_id\x18\x01 \x01(\tR\rtransactionId\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x125\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x1d.payment.v1.TransactionStatusR\x06status\x12=\n" +
+	"\fprocessed_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\vprocessedAt\" \n" +
+	"\x0eGetByIDRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x8d\x01\n" +
+	"\x13UpdateStatusRequest\x12%\n" +
+	"\x0etransaction_id\x18\x01 \x01(\tR\rtransactionId\x125\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x1d.payment.v1.TransactionStatusR\x06status\x12\x18\n" +
+	"\adetails\x18\x03 \x01(\tR\adetails\"\x16\n" +
+	"\x14UpdateStatusResponse\")\n" +
+	"\x17WatchTransactionRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xfe\x03\n" +
+	"\vTransaction\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12)\n" +
+	"\x06amount\x18\x02 \x01(\v2\x11.payment.v1.MoneyR\x06amount\x12:\n" +
+	"\fcard_details\x18\x03 \x01(\v2\x17.payment.v1.CardDetailsR\vcardDetails\x12C\n" +
+	"\x0fgateway_details\x18\x04 \x01(\v2\x1a.payment.v1.GatewayDetailsR\x0egatewayDetails\x12/\n" +
+	"\x04type\x18\x05 \x01(\x0e2\x1b.payment.v1.TransactionTypeR\x04type\x125\n" +
+	"\x06status\x18\x06 \x01(\x0e2\x1d.payment.v1.TransactionStatusR\x06status\x12\x1f\n" +
+	"\vexternal_id\x18\a \x01(\tR\n" +
+	"externalId\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x124\n" +
+	"\arouting\x18\n" +
+	" \x03(\v2\x1a.payment.v1.RoutingAttemptR\arouting*r\n" +
+	"\x0fTransactionType\x12 \n" +
+	"\x1cTRANSACTION_TYPE_UNSPECIFIED\x10\x00\x12\x1c\n" +
+	"\x18TRANSACTION_TYPE_DEPOSIT\x10\x01\x12\x1f\n" +
+	"\x1bTRANSACTION_TYPE_WITHDRAWAL\x10\x02*\xbb\x01\n" +
+	"\x11TransactionStatus\x12\"\n" +
+	"\x1eTRANSACTION_STATUS_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aTRANSACTION_STATUS_PENDING\x10\x01\x12!\n" +
+	"\x1dTRANSACTION_STATUS_PROCESSING\x10\x02\x12 \n" +
+	"\x1cTRANSACTION_STATUS_SUCCEEDED\x10\x03\x12\x1d\n" +
+	"\x19TRANSACTION_STATUS_FAILED\x10\x042\x91\x03\n" +
+	"\x12TransactionService\x12F\n" +
+	"\aDeposit\x12\x1a.payment.v1.DepositRequest\x1a\x1f.payment.v1.TransactionResponse\x12L\n" +
+	"\n" +
+	"Withdrawal\x12\x1d.payment.v1.WithdrawalRequest\x1a\x1f.payment.v1.TransactionResponse\x12>\n" +
+	"\aGetByID\x12\x1a.payment.v1.GetByIDRequest\x1a\x17.payment.v1.Transaction\x12Q\n" +
+	"\fUpdateStatus\x12\x1f.payment.v1.UpdateStatusRequest\x1a .payment.v1.UpdateStatusResponse\x12R\n" +
+	"\x10WatchTransaction\x12#.payment.v1.WatchTransactionRequest\x1a\x17.payment.v1.Transaction0\x01B0Z.go-payment-service/pkg/pb/payment/v1;paymentv1b\x06proto3"
+
+var (
+	file_payment_v1_transaction_proto_rawDescOnce sync.Once
+	file_payment_v1_transaction_proto_rawDescData []byte
+)
+
+func file_payment_v1_transaction_proto_rawDescGZIP() []byte {
+	file_payment_v1_transaction_proto_rawDescOnce.Do(func() {
+		file_payment_v1_transaction_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_payment_v1_transaction_proto_rawDesc), len(file_payment_v1_transaction_proto_rawDesc)))
+	})
+	return file_payment_v1_transaction_proto_rawDescData
+}
+
+var file_payment_v1_transaction_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_payment_v1_transaction_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_payment_v1_transaction_proto_goTypes = []any{
+	(TransactionType)(0),            // 0: payment.v1.TransactionType
+	(TransactionStatus)(0),          // 1: payment.v1.TransactionStatus
+	(*Money)(nil),                   // 2: payment.v1.Money
+	(*CardDetails)(nil),             // 3: payment.v1.CardDetails
+	(*GatewayDetails)(nil),          // 4: payment.v1.GatewayDetails
+	(*RoutingAttempt)(nil),          // 5: payment.v1.RoutingAttempt
+	(*DepositRequest)(nil),          // 6: payment.v1.DepositRequest
+	(*WithdrawalRequest)(nil),       // 7: payment.v1.WithdrawalRequest
+	(*TransactionResponse)(nil),     // 8: payment.v1.TransactionResponse
+	(*GetByIDRequest)(nil),          // 9: payment.v1.GetByIDRequest
+	(*UpdateStatusRequest)(nil),     // 10: payment.v1.UpdateStatusRequest
+	(*UpdateStatusResponse)(nil),    // 11: payment.v1.UpdateStatusResponse
+	(*WatchTransactionRequest)(nil), // 12: payment.v1.WatchTransactionRequest
+	(*Transaction)(nil),             // 13: payment.v1.Transaction
+	(*timestamppb.Timestamp)(nil),   // 14: google.protobuf.Timestamp
+}
+var file_payment_v1_transaction_proto_depIdxs = []int32{
+	14, // 0: payment.v1.RoutingAttempt.attempted_at:type_name -> google.protobuf.Timestamp
+	2,  // 1: payment.v1.DepositRequest.amount:type_name -> payment.v1.Money
+	3,  // 2: payment.v1.DepositRequest.card_details:type_name -> payment.v1.CardDetails
+	4,  // 3: payment.v1.DepositRequest.gateway_details:type_name -> payment.v1.GatewayDetails
+	2,  // 4: payment.v1.WithdrawalRequest.amount:type_name -> payment.v1.Money
+	3,  // 5: payment.v1.WithdrawalRequest.card_details:type_name -> payment.v1.CardDetails
+	4,  // 6: payment.v1.WithdrawalRequest.gateway_details:type_name -> payment.v1.GatewayDetails
+	1,  // 7: payment.v1.TransactionResponse.status:type_name -> payment.v1.TransactionStatus
+	14, // 8: payment.v1.TransactionResponse.processed_at:type_name -> google.protobuf.Timestamp
+	1,  // 9: payment.v1.UpdateStatusRequest.status:type_name -> payment.v1.TransactionStatus
+	2,  // 10: payment.v1.Transaction.amount:type_name -> payment.v1.Money
+	3,  // 11: payment.v1.Transaction.card_details:type_name -> payment.v1.CardDetails
+	4,  // 12: payment.v1.Transaction.gateway_details:type_name -> payment.v1.GatewayDetails
+	0,  // 13: payment.v1.Transaction.type:type_name -> payment.v1.TransactionType
+	1,  // 14: payment.v1.Transaction.status:type_name -> payment.v1.TransactionStatus
+	14, // 15: payment.v1.Transaction.created_at:type_name -> google.protobuf.Timestamp
+	14, // 16: payment.v1.Transaction.updated_at:type_name -> google.protobuf.Timestamp
+	5,  // 17: payment.v1.Transaction.routing:type_name -> payment.v1.RoutingAttempt
+	6,  // 18: payment.v1.TransactionService.Deposit:input_type -> payment.v1.DepositRequest
+	7,  // 19: payment.v1.TransactionService.Withdrawal:input_type -> payment.v1.WithdrawalRequest
+	9,  // 20: payment.v1.TransactionService.GetByID:input_type -> payment.v1.GetByIDRequest
+	10, // 21: payment.v1.TransactionService.UpdateStatus:input_type -> payment.v1.UpdateStatusRequest
+	12, // 22: payment.v1.TransactionService.WatchTransaction:input_type -> payment.v1.WatchTransactionRequest
+	8,  // 23: payment.v1.TransactionService.Deposit:output_type -> payment.v1.TransactionResponse
+	8,  // 24: payment.v1.TransactionService.Withdrawal:output_type -> payment.v1.TransactionResponse
+	13, // 25: payment.v1.TransactionService.GetByID:output_type -> payment.v1.Transaction
+	11, // 26: payment.v1.TransactionService.UpdateStatus:output_type -> payment.v1.UpdateStatusResponse
+	13, // 27: payment.v1.TransactionService.WatchTransaction:output_type -> payment.v1.Transaction
+	23, // [23:28] is the sub-list for method output_type
+	18, // [18:23] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
+}
+
+func init() { file_payment_v1_transaction_proto_init() }
+func file_payment_v1_transaction_proto_init() {
+	if File_payment_v1_transaction_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_payment_v1_transaction_proto_rawDesc), len(file_payment_v1_transaction_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_payment_v1_transaction_proto_goTypes,
+		DependencyIndexes: file_payment_v1_transaction_proto_depIdxs,
+		EnumInfos:         file_payment_v1_transaction_proto_enumTypes,
+		MessageInfos:      file_payment_v1_transaction_proto_msgTypes,
+	}.Build()
+	File_payment_v1_transaction_proto = out.File
+	file_payment_v1_transaction_proto_goTypes = nil
+	file_payment_v1_transaction_proto_depIdxs = nil
+}
@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderSignature carries the signature of a webhook payload, e.g. "t=1727710120,v1=5257a869..."
+	HeaderSignature = "X-Webhook-Signature"
+	// HeaderDeliveryID carries the ID of the webhook delivery, which is the same on every retry
+	HeaderDeliveryID = "X-Webhook-Delivery-ID"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredSignature = errors.New("expired webhook signature")
+)
+
+// Sign returns the signature header value of the payload: the timestamp and the
+// hex encoded HMAC-SHA256 of "<timestamp>.<payload>" using the shared secret
+func Sign(secret []byte, timestamp time.Time, payload []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+
+	return fmt.Sprintf("t=%s,v1=%s", t, computeSignature(secret, t, payload))
+}
+
+// Verify checks the signature header value against the payload, rejecting
+// signatures older than the tolerance to prevent replay attacks (0 disables the check)
+func Verify(secret []byte, header string, payload []byte, tolerance time.Duration) error {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			v1 = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || v1 == "" {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(v1), []byte(computeSignature(secret, t, payload))) {
+		return ErrInvalidSignature
+	}
+
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)) > tolerance {
+		return ErrExpiredSignature
+	}
+
+	return nil
+}
+
+func computeSignature(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
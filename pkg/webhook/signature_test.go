@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TestSignatureSuite struct {
+	suite.Suite
+}
+
+func (suite *TestSignatureSuite) TestVerify() {
+	secret := []byte("secret")
+	payload := []byte(`{"transactionId":"tx-1","status":"succeeded"}`)
+	now := time.Now()
+
+	testCases := []struct {
+		name           string
+		givenSecret    []byte
+		givenHeader    string
+		givenPayload   []byte
+		givenTolerance time.Duration
+		expectedErr    error
+	}{
+		{
+			name:         "valid signature",
+			givenSecret:  secret,
+			givenHeader:  Sign(secret, now, payload),
+			givenPayload: payload,
+		},
+		{
+			name:         "tampered body",
+			givenSecret:  secret,
+			givenHeader:  Sign(secret, now, payload),
+			givenPayload: []byte(`{"transactionId":"tx-1","status":"failed"}`),
+			expectedErr:  ErrInvalidSignature,
+		},
+		{
+			name:         "wrong secret",
+			givenSecret:  []byte("other"),
+			givenHeader:  Sign(secret, now, payload),
+			givenPayload: payload,
+			expectedErr:  ErrInvalidSignature,
+		},
+		{
+			name:         "tampered timestamp",
+			givenSecret:  secret,
+			givenHeader:  strings.Replace(Sign(secret, now, payload), fmt.Sprintf("t=%d", now.Unix()), fmt.Sprintf("t=%d", now.Unix()+1), 1),
+			givenPayload: payload,
+			expectedErr:  ErrInvalidSignature,
+		},
+		{
+			name:         "malformed header",
+			givenSecret:  secret,
+			givenHeader:  "v1=abc",
+			givenPayload: payload,
+			expectedErr:  ErrInvalidSignature,
+		},
+		{
+			name:           "expired signature",
+			givenSecret:    secret,
+			givenHeader:    Sign(secret, now.Add(-10*time.Minute), payload),
+			givenPayload:   payload,
+			givenTolerance: 5 * time.Minute,
+			expectedErr:    ErrExpiredSignature,
+		},
+		{
+			name:           "old signature without tolerance",
+			givenSecret:    secret,
+			givenHeader:    Sign(secret, now.Add(-10*time.Minute), payload),
+			givenPayload:   payload,
+			givenTolerance: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.givenSecret, tc.givenHeader, tc.givenPayload, tc.givenTolerance)
+
+			if tc.expectedErr != nil {
+				suite.ErrorIs(err, tc.expectedErr)
+				return
+			}
+
+			suite.NoError(err)
+		})
+	}
+}
+
+func TestTestSignatureSuite(t *testing.T) {
+	suite.Run(t, new(TestSignatureSuite))
+}
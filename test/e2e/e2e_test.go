@@ -2,34 +2,50 @@ package e2e
 
 import (
 	"bytes"
+	"encoding/json"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 
 	"go-payment-service/internal/app"
+	"go-payment-service/internal/config"
 	paymenthttp "go-payment-service/pkg/http"
 	"go-payment-service/pkg/model"
 )
 
 type TestE2ESuite struct {
 	suite.Suite
-	client paymenthttp.HTTPClient
-	server *httptest.Server
+	client   paymenthttp.HTTPClient
+	server   *httptest.Server
+	merchant *httptest.Server
+
+	mu       sync.Mutex
+	webhooks map[string]model.WebhookEvent
 }
 
 // It runs before all tests
 func (suite *TestE2ESuite) SetupSuite() {
-	srv := app.NewServer()
+	cfg := config.Default()
+	cfg.Auth.Disabled = true
+	// the merchant test server listens on a loopback http URL
+	cfg.Webhooks.AllowPrivateURLs = true
+
+	srv := app.NewServer(cfg)
 	suite.server = srv.StartTest()
 	suite.client = paymenthttp.NewResilientHTTPClient()
+	suite.webhooks = make(map[string]model.WebhookEvent)
+	suite.merchant = suite.newMerchantHTTPTestServer()
 }
 
 // It runs after all tests
 func (suite *TestE2ESuite) TearDownSuite() {
 	suite.server.Close()
+	suite.merchant.Close()
 }
 
 func (suite *TestE2ESuite) TestDeposit() {
@@ -112,7 +128,7 @@ func (suite *TestE2ESuite) TestDeposit() {
 					GatewayDetails: model.GatewayDetails{
 						ID:          "gatewayA",
 						Name:        "Gateway A",
-						CallbackURL: suite.server.URL + "/callback",
+						CallbackURL: suite.merchant.URL + "/webhooks",
 					},
 				},
 			},
@@ -140,7 +156,7 @@ func (suite *TestE2ESuite) TestDeposit() {
 					GatewayDetails: model.GatewayDetails{
 						ID:          "gatewayB",
 						Name:        "Gateway B",
-						CallbackURL: suite.server.URL + "/callback",
+						CallbackURL: suite.merchant.URL + "/webhooks",
 					},
 				},
 			},
@@ -192,6 +208,7 @@ func (suite *TestE2ESuite) TestDeposit() {
 				suite.NoError(err)
 
 				suite.Equal(expectedStatus, tr.Status)
+				suite.assertWebhookReceived(dr.TransactionID, expectedStatus)
 
 				jsonBytes, err := paymenthttp.Marshal(tc.givenMIMEType, tr)
 				suite.NoError(err)
@@ -284,7 +301,7 @@ func (suite *TestE2ESuite) TestWithdrawal() {
 					GatewayDetails: model.GatewayDetails{
 						ID:          "gatewayA",
 						Name:        "Gateway A",
-						CallbackURL: suite.server.URL + "/callback",
+						CallbackURL: suite.merchant.URL + "/webhooks",
 					},
 				},
 			},
@@ -312,7 +329,7 @@ func (suite *TestE2ESuite) TestWithdrawal() {
 					GatewayDetails: model.GatewayDetails{
 						ID:          "gatewayB",
 						Name:        "Gateway B",
-						CallbackURL: suite.server.URL + "/callback",
+						CallbackURL: suite.merchant.URL + "/webhooks",
 					},
 				},
 			},
@@ -364,6 +381,7 @@ func (suite *TestE2ESuite) TestWithdrawal() {
 				suite.NoError(err)
 
 				suite.Equal(expectedStatus, tr.Status)
+				suite.assertWebhookReceived(dr.TransactionID, expectedStatus)
 				return
 			}
 
@@ -372,6 +390,39 @@ func (suite *TestE2ESuite) TestWithdrawal() {
 	}
 }
 
+// assertWebhookReceived waits for the merchant to be notified of the transaction status
+func (suite *TestE2ESuite) assertWebhookReceived(transactionID string, status model.TransactionStatus) {
+	suite.Eventually(func() bool {
+		suite.mu.Lock()
+		defer suite.mu.Unlock()
+
+		event, ok := suite.webhooks[transactionID]
+		return ok && event.Status == status
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func (suite *TestE2ESuite) newMerchantHTTPTestServer() *httptest.Server {
+	// Initialize HTTP request multiplexer
+	mux := http.NewServeMux()
+
+	// Routes
+	mux.HandleFunc("POST /webhooks", func(w http.ResponseWriter, r *http.Request) {
+		var event model.WebhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		suite.mu.Lock()
+		suite.webhooks[event.TransactionID] = event
+		suite.mu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return httptest.NewServer(mux)
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestTestE2ESuite(t *testing.T) {
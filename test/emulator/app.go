@@ -8,7 +8,14 @@ import (
 	paymenthttp "go-payment-service/pkg/http"
 )
 
+// Start starts the emulator, sending its callbacks without authentication
 func Start() *httptest.Server {
+	return StartWithCallbackSecret("")
+}
+
+// StartWithCallbackSecret starts the emulator, sending the secret shared with the payment service
+// with its callbacks, e.g. "Authorization: Bearer <secret>"
+func StartWithCallbackSecret(secret string) *httptest.Server {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
@@ -18,6 +25,7 @@ func Start() *httptest.Server {
 	client := paymenthttp.NewResilientHTTPClient()
 	memoryRepository := newMemoryRepository()
 	service := newService(client, memoryRepository)
+	service.callbackSecret = secret
 	handler := newHandler(service)
 	server := newServer(handler)
 
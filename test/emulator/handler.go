@@ -44,6 +44,11 @@ func (h *handler) process(w http.ResponseWriter, r *http.Request) {
 	// to handle multiple formats in the callback
 	ctx := context.WithValue(r.Context(), ContextKey(ContextKeyContentType), contentType)
 
+	// send the request ID back with the callback, to correlate it with the transaction
+	if id := r.Header.Get(paymenthttp.HeaderRequestID); id != "" {
+		ctx = paymenthttp.WithRequestID(ctx, id)
+	}
+
 	// decode request
 	var req ProcessRequest
 	if err := paymenthttp.Decode(r.Body, contentType, &req); err != nil {
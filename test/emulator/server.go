@@ -2,6 +2,8 @@ package emulator
 
 import (
 	"net/http/httptest"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type server struct {
@@ -10,6 +12,7 @@ type server struct {
 
 func newServer(handler *handler) server {
 	return server{
-		httpServer: httptest.NewServer(handler.mux),
+		// continues the traces of the payment service, up to the callbacks
+		httpServer: httptest.NewServer(otelhttp.NewHandler(handler.mux, "emulator")),
 	}
 }
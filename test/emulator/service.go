@@ -21,9 +21,11 @@ type Service interface {
 type service struct {
 	client     paymenthttp.HTTPClient
 	repository Repository
+	// callbackSecret authenticates the callbacks, none when empty
+	callbackSecret string
 }
 
-func newService(client paymenthttp.HTTPClient, repository Repository) Service {
+func newService(client paymenthttp.HTTPClient, repository Repository) *service {
 	return &service{
 		client:     client,
 		repository: repository,
@@ -71,7 +73,8 @@ func (s *service) sendTransactionUpdate(ctx context.Context, tx Transaction) {
 		slog.Any("callback-url", tx.CallbackURL),
 	)
 
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	// in the trace of the processing request, without being cancelled once it completes
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
 
 	go func() {
 		defer cancel()
@@ -97,13 +100,16 @@ func (s *service) sendTransactionUpdate(ctx context.Context, tx Transaction) {
 
 			slog.Info("emulator: sending request", slog.Any("payload", string(b)))
 
-			req, err := http.NewRequest(http.MethodPost, tx.CallbackURL, bytes.NewBuffer(b))
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, tx.CallbackURL, bytes.NewBuffer(b))
 			if err != nil {
 				slog.Error("emulator: failed to create HTTP request", slog.Any("error", err))
 				return
 			}
 
 			req.Header.Add(paymenthttp.HeaderContentType, contentType)
+			if s.callbackSecret != "" {
+				req.Header.Add(paymenthttp.HeaderAuthorization, "Bearer "+s.callbackSecret)
+			}
 
 			resp, err := s.client.Do(req)
 			if err != nil {